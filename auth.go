@@ -0,0 +1,33 @@
+package spirytus
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// BearerToken returns the token from an "Authorization: Bearer <token>"
+// request header. The scheme is matched case-insensitively and surrounding
+// whitespace is ignored. The boolean is false if the header is absent, uses
+// another scheme or does not contain exactly one token.
+//
+// BearerToken only parses the header; verifying the token is up to the caller.
+func BearerToken(req *http.Request) (string, bool) {
+	fields := strings.Fields(req.Header.Get("Authorization"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// WithToken returns a copy of ctx carrying token. It is intended for
+// middleware that has verified the token returned by BearerToken.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// TokenFromContext returns the token stored in ctx by WithToken.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
@@ -0,0 +1,105 @@
+package spirytus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// JSONRequestBounded decodes a request body containing an arbitrary JSON
+// object, guarding against bodies crafted to exhaust the server. The body may
+// be at most maxBytes long, or an error wrapping ErrBodyTooLarge is returned,
+// and objects and arrays may be nested at most maxDepth deep, counting the
+// top-level object, or ErrTooDeep is returned. The depth is checked while the
+// body is read, before the nested values are built. A body that is not an
+// object, or that is followed by anything but white space, results in an
+// error wrapping ErrMalformedJSON.
+func JSONRequestBounded(req *http.Request, maxBytes int64, maxDepth int) (map[string]interface{}, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, req.Body, maxBytes))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, boundedError(err)
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("%w: body is not an object", ErrMalformedJSON)
+	}
+	v, err := decodeBounded(dec, tok, 0, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	switch _, err := dec.Token(); err {
+	case io.EOF:
+	case nil:
+		return nil, fmt.Errorf("%w: data after top-level object", ErrMalformedJSON)
+	default:
+		return nil, boundedError(err)
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// decodeBounded decodes the value starting with tok at the given depth.
+func decodeBounded(dec *json.Decoder, tok json.Token, depth, maxDepth int) (interface{}, error) {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	if depth++; depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+	next := func() (json.Token, error) {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return tok, boundedError(err)
+	}
+
+	var v interface{}
+	switch delim {
+	case '{':
+		m := make(map[string]interface{})
+		for dec.More() {
+			key, err := next()
+			if err != nil {
+				return nil, err
+			}
+			tok, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if m[key.(string)], err = decodeBounded(dec, tok, depth, maxDepth); err != nil {
+				return nil, err
+			}
+		}
+		v = m
+	case '[':
+		a := []interface{}{}
+		for dec.More() {
+			tok, err := next()
+			if err != nil {
+				return nil, err
+			}
+			e, err := decodeBounded(dec, tok, depth, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, e)
+		}
+		v = a
+	}
+	// Consume the closing delimiter.
+	if _, err := next(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func boundedError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return errors.Join(ErrBodyTooLarge, err)
+	}
+	return decodeError(err)
+}
@@ -0,0 +1,42 @@
+package spirytus
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONRequestBounded(t *testing.T) {
+	tests := []struct {
+		name, body string
+		err        error
+	}{
+		{"object", `{"a":[1,{"b":null}],"c":"d"}`, nil},
+		{"trailing space", "{\"a\":1} \n\t", nil},
+		{"empty", ``, ErrEmptyBody},
+		{"not object", `[1]`, ErrMalformedJSON},
+		{"trailing object", `{}{}`, ErrMalformedJSON},
+		{"trailing garbage", `{"a":1}x`, ErrMalformedJSON},
+		{"trailing value", `{"a":1} 2`, ErrMalformedJSON},
+		{"unterminated", `{"a":[1,2`, ErrMalformedJSON},
+		{"at depth", `{"a":{"b":[1]}}`, nil},
+		{"too deep", `{"a":{"b":[[1]]}}`, ErrTooDeep},
+		{"deep arrays", `{"a":` + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + `}`, ErrTooDeep},
+		{"deep unterminated", `{"a":` + strings.Repeat("[", 100000), ErrTooDeep},
+		{"too large", `{"a":"` + strings.Repeat("x", 1<<16) + `"}`, ErrBodyTooLarge},
+		{"wide", `{"a":[` + strings.Repeat("[],", 10000) + `[]]}`, nil},
+		{"trailing too large", `{}` + strings.Repeat(" ", 1<<16) + `x`, ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		v, err := JSONRequestBounded(req, 1<<16, 3)
+		if tt.err == nil {
+			if err != nil || v == nil {
+				t.Errorf("%s: got %v, %v", tt.name, v, err)
+			}
+		} else if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package spirytus
+
+import (
+	"time"
+)
+
+// A Clock tells the current time. Features that depend on the time accept a
+// Clock so that tests can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// clockNow returns the current time according to c, or the real time if c is nil.
+func clockNow(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
@@ -0,0 +1,18 @@
+package spirytus
+
+// contextKey is the type of the keys under which the package stores values
+// in a request context. Being unexported, it cannot collide with keys
+// defined by other packages.
+type contextKey int
+
+const (
+	tokenKey contextKey = iota
+	errorsKey
+	versionKey
+	prefixKey
+	requestIDKey
+	clientIPKey
+	csrfKey
+	timingKey
+	languageKey
+)
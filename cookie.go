@@ -0,0 +1,44 @@
+package spirytus
+
+import (
+	"net/http"
+)
+
+// CookieAttributes are the cookie attributes that SetCookie can fill in by
+// default.
+type CookieAttributes struct {
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+	Secure   bool
+	HttpOnly bool
+}
+
+// CookieDefaults are applied by SetCookie to every cookie field that is left
+// at its zero value. A non-zero field on the cookie always takes precedence
+// over the default. Since Secure and HttpOnly are booleans, a default of true
+// cannot be turned off for an individual cookie; use http.SetCookie directly
+// for such cookies.
+//
+// CookieDefaults should be set during program initialisation. Modifying it
+// while requests are being served is a data race.
+var CookieDefaults CookieAttributes
+
+// SetCookie adds a Set-Cookie header for c to w after filling in any unset
+// attributes from CookieDefaults. The cookie passed in is not modified.
+func SetCookie(w http.ResponseWriter, c *http.Cookie) {
+	cookie := *c
+	d := CookieDefaults
+	if cookie.Path == "" {
+		cookie.Path = d.Path
+	}
+	if cookie.Domain == "" {
+		cookie.Domain = d.Domain
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = d.SameSite
+	}
+	cookie.Secure = cookie.Secure || d.Secure
+	cookie.HttpOnly = cookie.HttpOnly || d.HttpOnly
+	http.SetCookie(w, &cookie)
+}
@@ -0,0 +1,126 @@
+package spirytus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// CSRFOptions configures the CSRF middleware. Empty fields take the defaults
+// given below.
+type CSRFOptions struct {
+	// CookieName is the name of the cookie holding the token.
+	// The default is "csrf_token".
+	CookieName string
+
+	// HeaderName is the request header in which clients echo the token.
+	// The default is "X-CSRF-Token".
+	HeaderName string
+
+	// FormField is the form field in which clients may echo the token
+	// instead. Only application/x-www-form-urlencoded bodies are searched
+	// for it; clients sending multipart forms must use the header.
+	// The default is "csrf_token".
+	FormField string
+
+	// MaxFormBytes limits the size of a form body read in search of the
+	// token. The default is 1 MiB.
+	MaxFormBytes int64
+
+	// ExemptPaths lists request paths that are not checked.
+	ExemptPaths []string
+}
+
+// CSRF returns a middleware that protects against cross-site request forgery
+// using the double-submit cookie pattern. Requests with safe methods (GET,
+// HEAD, OPTIONS and TRACE) are given a random token in a cookie if they lack
+// one, and the token is available to the handler through CSRFToken so it can
+// be embedded in forms. Requests with any other method must echo the cookie's
+// token in the configured header or form field, or they are rejected with
+// 403 Forbidden. The form field is only consulted for URL-encoded forms, so
+// that the middleware never spools a multipart body ahead of the handler.
+//
+// The cookie is set with Path=/, Secure, SameSite=Lax and the Domain of
+// CookieDefaults. It is never HttpOnly, whatever CookieDefaults says, so that
+// scripts can read it to fill in the header.
+func CSRF(opts CSRFOptions) Middleware {
+	if opts.CookieName == "" {
+		opts.CookieName = "csrf_token"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FormField == "" {
+		opts.FormField = "csrf_token"
+	}
+	if opts.MaxFormBytes == 0 {
+		opts.MaxFormBytes = 1 << 20
+	}
+	exempt := make(map[string]bool, len(opts.ExemptPaths))
+	for _, p := range opts.ExemptPaths {
+		exempt[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var token string
+			if c, err := req.Cookie(opts.CookieName); err == nil {
+				token = c.Value
+			}
+
+			switch req.Method {
+			case "GET", "HEAD", "OPTIONS", "TRACE":
+				if token == "" {
+					var err error
+					if token, err = newCSRFToken(); err != nil {
+						jsonError(w, http.StatusInternalServerError, "Internal server error")
+						return
+					}
+					http.SetCookie(w, &http.Cookie{
+						Name:     opts.CookieName,
+						Value:    token,
+						Path:     "/",
+						Domain:   CookieDefaults.Domain,
+						Secure:   true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+			default:
+				if exempt[req.URL.Path] {
+					break
+				}
+				sent := req.Header.Get(opts.HeaderName)
+				if sent == "" && hasMediaType(req, "application/x-www-form-urlencoded") {
+					req.Body = http.MaxBytesReader(w, req.Body, opts.MaxFormBytes)
+					if req.ParseForm() == nil {
+						sent = req.PostForm.Get(opts.FormField)
+					}
+				}
+				if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					jsonError(w, http.StatusForbidden, "Invalid CSRF token")
+					return
+				}
+			}
+
+			ctx := context.WithValue(req.Context(), csrfKey, token)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CSRFToken returns the CSRF token of the request, as established by the CSRF
+// middleware.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfKey).(string)
+	return token
+}
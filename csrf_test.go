@@ -0,0 +1,54 @@
+package spirytus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFFormField(t *testing.T) {
+	h := CSRF(CSRFOptions{MaxFormBytes: 64})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.MultipartForm != nil {
+			t.Error("multipart form parsed before the handler")
+		}
+	}))
+	tests := []struct {
+		name, contentType, body string
+		want                    int
+	}{
+		{"form", "application/x-www-form-urlencoded", "csrf_token=tok", 200},
+		{"wrong token", "application/x-www-form-urlencoded", "csrf_token=bad", 403},
+		{"too large", "application/x-www-form-urlencoded", "x=" + strings.Repeat("a", 64) + "&csrf_token=tok", 403},
+		{"multipart", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"csrf_token\"\r\n\r\ntok\r\n--b--\r\n", 403},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "tok"})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestCSRFCookieNotHttpOnly(t *testing.T) {
+	defer func(d CookieAttributes) { CookieDefaults = d }(CookieDefaults)
+	CookieDefaults = CookieAttributes{Domain: "example.com", Path: "/app", HttpOnly: true}
+
+	w := httptest.NewRecorder()
+	CSRF(CSRFOptions{})(noopHandler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.HttpOnly {
+		t.Error("CSRF cookie is HttpOnly")
+	}
+	if c.Domain != "example.com" || c.Path != "/" || !c.Secure || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("got cookie %v", c)
+	}
+}
@@ -0,0 +1,59 @@
+package spirytus
+
+import (
+	"encoding/csv"
+	"net/http"
+)
+
+// csvFlushRows is the number of rows CSVStream writes between flushes.
+const csvFlushRows = 100
+
+// CSVStream writes a CSV response with the provided status code, consisting of
+// the header row, if not nil, followed by the rows produced by the iterator.
+// Rows are flushed to the client as they are written, so large exports need
+// not be held in memory:
+//
+//	spirytus.CSVStream(w, http.StatusOK, []string{"id", "name"}, func(yield func([]string) bool) {
+//		for rows.Next() {
+//			...
+//			if !yield(record) {
+//				return
+//			}
+//		}
+//	})
+//
+// Iteration stops at the first write error, which is returned.
+func CSVStream(w http.ResponseWriter, code int, header []string, rows func(yield func([]string) bool)) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(code)
+
+	cw := csv.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	var err error
+	if header != nil {
+		err = cw.Write(header)
+	}
+	if err == nil {
+		n := 0
+		rows(func(record []string) bool {
+			if err = cw.Write(record); err != nil {
+				return false
+			}
+			if n++; n%csvFlushRows == 0 {
+				cw.Flush()
+				if err = cw.Error(); err != nil {
+					return false
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return true
+		})
+	}
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return err
+}
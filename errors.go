@@ -0,0 +1,107 @@
+package spirytus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrEmptyBody is returned by JSONRequest when the request has no body.
+	// It wraps io.EOF.
+	ErrEmptyBody = fmt.Errorf("spirytus: empty request body: %w", io.EOF)
+
+	// ErrMalformedJSON is wrapped by errors returned from JSONRequest when
+	// the request body is not valid JSON. For syntax errors the underlying
+	// *json.SyntaxError is wrapped too and the message includes its offset.
+	ErrMalformedJSON = errors.New("spirytus: malformed JSON")
+)
+
+// ErrUnsupportedMediaType is returned by request helpers when the request
+// body does not have the media type they expect. Handlers typically respond
+// with 415 Unsupported Media Type.
+var ErrUnsupportedMediaType = errors.New("spirytus: unsupported media type")
+
+// ErrBodyTooLarge is returned by request helpers when the request body
+// exceeds the permitted size. Handlers typically respond with 413 Content Too
+// Large.
+var ErrBodyTooLarge = errors.New("spirytus: request body too large")
+
+// ErrTooDeep is returned by JSONRequestBounded when the request body nests
+// objects and arrays deeper than permitted.
+var ErrTooDeep = errors.New("spirytus: JSON nested too deeply")
+
+// ErrPushNotSupported is returned by Push when the connection does not
+// support HTTP/2 server push.
+var ErrPushNotSupported = errors.New("spirytus: server push not supported")
+
+// decodeError translates an error returned by json.Decoder.Decode in to one
+// of the package's sentinel errors where possible.
+func decodeError(err error) error {
+	var syntax *json.SyntaxError
+	switch {
+	case err == io.EOF:
+		return ErrEmptyBody
+	case err == io.ErrUnexpectedEOF:
+		return fmt.Errorf("%w: %w", ErrMalformedJSON, err)
+	case errors.As(err, &syntax):
+		return fmt.Errorf("%w at offset %d: %w", ErrMalformedJSON, syntax.Offset, err)
+	}
+	return err
+}
+
+// An ErrorList collects recoverable errors encountered while handling a
+// request, so that middleware can observe them after the handler returns,
+// for example for logging or metrics. It is a diagnostic aid only and does not
+// affect the response. An ErrorList is safe for concurrent use, and the
+// methods of a nil *ErrorList do nothing.
+type ErrorList struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add appends err to the list. Nil errors are ignored.
+func (l *ErrorList) Add(err error) {
+	if l == nil || err == nil {
+		return
+	}
+	l.mu.Lock()
+	l.errs = append(l.errs, err)
+	l.mu.Unlock()
+}
+
+// Errors returns the errors added so far.
+func (l *ErrorList) Errors() []error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errs...)
+}
+
+// Err returns the errors added so far joined in to one, or nil if there are
+// none.
+func (l *ErrorList) Err() error {
+	return errors.Join(l.Errors()...)
+}
+
+// WithErrors returns a copy of ctx carrying a new ErrorList, which is also
+// returned so the caller can inspect it once the handler is done.
+func WithErrors(ctx context.Context) (context.Context, *ErrorList) {
+	l := new(ErrorList)
+	return context.WithValue(ctx, errorsKey, l), l
+}
+
+// ErrorsFromContext returns the ErrorList stored in ctx by WithErrors, or nil
+// if there is none. Since a nil list ignores additions, handlers can record
+// errors unconditionally:
+//
+//	spirytus.ErrorsFromContext(req.Context()).Add(err)
+func ErrorsFromContext(ctx context.Context) *ErrorList {
+	l, _ := ctx.Value(errorsKey).(*ErrorList)
+	return l
+}
@@ -0,0 +1,133 @@
+package spirytus
+
+import (
+	"net/http"
+	"strings"
+)
+
+// scanETag returns the entity tag at the start of s, such as `"xyz"` or
+// `W/"xyz"`, and the remainder of s. The tag is empty if s does not start
+// with a valid entity tag.
+func scanETag(s string) (etag string, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s[start:]) < 2 || s[start] != '"' {
+		return "", ""
+	}
+	for i := start + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return s[:i+1], s[i+1:]
+		case c == 0x21 || c >= 0x23 && c != 0x7f:
+			// Valid etagc.
+		default:
+			return "", ""
+		}
+	}
+	return "", ""
+}
+
+// strongMatch reports whether a and b match under the strong comparison
+// function of RFC 7232: neither is weak and their opaque tags are equal.
+func strongMatch(a, b string) bool {
+	return a == b && a != "" && !strings.HasPrefix(a, "W/")
+}
+
+// weakMatch reports whether a and b match under the weak comparison
+// function of RFC 7232: their opaque tags are equal, whether or not either is
+// weak.
+func weakMatch(a, b string) bool {
+	a = strings.TrimPrefix(a, "W/")
+	return a == strings.TrimPrefix(b, "W/") && a != ""
+}
+
+// matchETagList reports whether the header value, a comma-separated list of
+// entity tags, contains one that matches etag according to match.
+func matchETagList(header, etag string, match func(a, b string) bool) bool {
+	for {
+		header = strings.TrimLeft(header, " \t")
+		if header == "" {
+			return false
+		}
+		if header[0] == ',' {
+			header = header[1:]
+			continue
+		}
+		var tag string
+		tag, header = scanETag(header)
+		if tag == "" {
+			return false
+		}
+		if match(tag, etag) {
+			return true
+		}
+	}
+}
+
+// IfMatch evaluates the If-Match precondition of req against currentETag,
+// the entity tag of the resource as it would appear in the ETag header.
+// An empty currentETag means the resource does not exist.
+//
+// If the precondition holds IfMatch returns true and the handler may proceed
+// with the update. Otherwise a 412 Precondition Failed response is written
+// and IfMatch returns false. A request without an If-Match header always
+// proceeds, and "If-Match: *" matches any existing resource.
+func IfMatch(w http.ResponseWriter, req *http.Request, currentETag string) bool {
+	header := req.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	if strings.TrimSpace(header) == "*" {
+		if currentETag != "" {
+			return true
+		}
+	} else if matchETagList(header, currentETag, strongMatch) {
+		return true
+	}
+	jsonError(w, http.StatusPreconditionFailed, "Precondition failed")
+	return false
+}
+
+// IfNoneMatch reports whether the If-None-Match header of req matches etag,
+// the current entity tag of the resource, meaning that a GET or HEAD request
+// should receive 304 Not Modified. The header may be "*", which matches any
+// existing resource, or a list of entity tags which are compared to etag with
+// the weak comparison function of RFC 7232, so W/"x" matches "x". An empty
+// etag means the resource does not exist and never matches.
+func IfNoneMatch(req *http.Request, etag string) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	return matchETagList(header, etag, weakMatch)
+}
+
+// CheckETag sets the ETag header of the response to etag and evaluates the
+// If-None-Match precondition of req against it, so that a handler that can
+// compute the entity tag cheaply may skip building the body entirely:
+//
+//	if spirytus.CheckETag(w, req, etag) {
+//		return
+//	}
+//
+// If the precondition fails, CheckETag writes 304 Not Modified for GET and
+// HEAD requests, or 412 Precondition Failed for other methods, and returns
+// true to tell the handler the response is complete.
+func CheckETag(w http.ResponseWriter, req *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if !IfNoneMatch(req, etag) {
+		return false
+	}
+	if req.Method == "GET" || req.Method == "HEAD" {
+		w.WriteHeader(http.StatusNotModified)
+	} else {
+		jsonError(w, http.StatusPreconditionFailed, "Precondition failed")
+	}
+	return true
+}
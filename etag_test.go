@@ -0,0 +1,72 @@
+package spirytus
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		header, etag string
+		want         bool
+	}{
+		{``, `"x"`, false},
+		{`"x"`, `"x"`, true},
+		{`W/"x"`, `"x"`, true},
+		{`"x"`, `W/"x"`, true},
+		{`"y"`, `"x"`, false},
+		{` "a" ,"b",	 W/"x" `, `"x"`, true},
+		{`"a", "b"`, `"x"`, false},
+		{`,, "x"`, `"x"`, true},
+		{`*`, `"x"`, true},
+		{` * `, `"x"`, true},
+		{`*`, ``, false},
+		{`"x"`, ``, false},
+		{`"a", x, "x"`, `"x"`, false},
+		{`"x`, `"x"`, false},
+		{`x`, `x`, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("If-None-Match", tt.header)
+		}
+		if got := IfNoneMatch(req, tt.etag); got != tt.want {
+			t.Errorf("IfNoneMatch(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header, etag string
+		want         bool
+	}{
+		{``, `"x"`, true},
+		{``, ``, true},
+		{`"x"`, `"x"`, true},
+		{`W/"x"`, `"x"`, false},
+		{`"x"`, `W/"x"`, false},
+		{`W/"x"`, `W/"x"`, false},
+		{` "a" ,"b",	 "x" `, `"x"`, true},
+		{`"a", "b"`, `"x"`, false},
+		{`*`, `"x"`, true},
+		{`*`, ``, false},
+		{`"x"`, ``, false},
+		{`"a", x, "x"`, `"x"`, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("If-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+		got := IfMatch(w, req, tt.etag)
+		if got != tt.want {
+			t.Errorf("IfMatch(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+		if !got && w.Code != 412 {
+			t.Errorf("IfMatch(%q, %q) wrote %d, want 412", tt.header, tt.etag, w.Code)
+		}
+	}
+}
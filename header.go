@@ -0,0 +1,150 @@
+package spirytus
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hopHeaders are the hop-by-hop headers defined by RFC 7230, which apply to a
+// single connection and must not be forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders deletes the hop-by-hop headers from h, along with any
+// header named in its Connection header.
+func removeHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// StripHopByHop removes hop-by-hop headers from requests before passing them
+// to next: the standard ones such as Connection and Keep-Alive and any header
+// listed in the Connection header. Since Upgrade is among them, it should not
+// wrap handlers that upgrade connections.
+func StripHopByHop(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		removeHopHeaders(req.Header)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// DefaultCacheControl returns a middleware that sets the Cache-Control header
+// of responses to GET and HEAD requests to value, unless the handler has set
+// it itself by the time the response header is written. Responses to other
+// methods are left alone.
+func DefaultCacheControl(value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			rw := &responseWriter{ResponseWriter: w, beforeHeader: func(h http.Header) {
+				if _, ok := h["Cache-Control"]; !ok {
+					h.Set("Cache-Control", value)
+				}
+			}}
+			next.ServeHTTP(rw, req)
+			rw.finish()
+		})
+	}
+}
+
+// RetryAfter sets the Retry-After header of w to tell the client how long to
+// wait before retrying, as a number of seconds rounded up. It is meant for
+// 429 Too Many Requests and 503 Service Unavailable responses.
+func RetryAfter(w http.ResponseWriter, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	secs := int64((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// RetryAfterTime sets the Retry-After header of w to the time after which the
+// client may retry, as an HTTP-date.
+func RetryAfterTime(w http.ResponseWriter, t time.Time) {
+	w.Header().Set("Retry-After", t.UTC().Format(http.TimeFormat))
+}
+
+// Deprecate advertises that the resource is deprecated. It sets the
+// Deprecation header (RFC 9745) to the time the resource was deprecated and
+// the Sunset header (RFC 8594) to the HTTP-date after which it may stop
+// responding, omitting either if its time is zero, and adds a Link header
+// with rel="deprecation" pointing to documentation, unless link is empty.
+//
+// To mark every response of a Resource, set the same headers with
+// SetDefaultHeader.
+func Deprecate(w http.ResponseWriter, deprecated, sunset time.Time, link string) {
+	h := w.Header()
+	if !deprecated.IsZero() {
+		h.Set("Deprecation", "@"+strconv.FormatInt(deprecated.Unix(), 10))
+	}
+	if !sunset.IsZero() {
+		h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if link != "" {
+		h.Add("Link", "<"+link+`>; rel="deprecation"`)
+	}
+}
+
+// CopyHeaders copies the named headers of the request src to the response
+// dst, for example to echo X-Request-ID or tracing headers. Every value of a
+// header is added, headers absent from the request are skipped and
+// hop-by-hop headers are never copied: neither the standard ones such as
+// Connection nor those the request lists in its Connection header.
+func CopyHeaders(dst http.ResponseWriter, src *http.Request, names ...string) {
+	h := dst.Header()
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		if isHopHeader(name) || inConnection(src.Header, name) {
+			continue
+		}
+		for _, v := range src.Header.Values(name) {
+			h.Add(name, v)
+		}
+	}
+}
+
+// inConnection reports whether the canonical header name is listed in the
+// Connection header of h.
+func inConnection(h http.Header, name string) bool {
+	for _, v := range h.Values("Connection") {
+		for _, n := range strings.Split(v, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(n)) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHopHeader reports whether the canonical header name is a standard
+// hop-by-hop header.
+func isHopHeader(name string) bool {
+	for _, hop := range hopHeaders {
+		if name == hop {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package spirytus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStripHopByHop(t *testing.T) {
+	var got http.Header
+	h := StripHopByHop(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Connection", "keep-alive, X-Custom")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("X-Custom", "secret")
+	req.Header.Set("X-Other", "kept")
+	req.Header.Set("Accept", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, name := range []string{"Connection", "Keep-Alive", "X-Custom"} {
+		if v, ok := got[name]; ok {
+			t.Errorf("%s not stripped: %q", name, v)
+		}
+	}
+	for name, want := range map[string]string{"X-Other": "kept", "Accept": "application/json"} {
+		if v := got.Get(name); v != want {
+			t.Errorf("%s = %q, want %q", name, v, want)
+		}
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	w := httptest.NewRecorder()
+	Deprecate(w, time.Unix(1700000000, 0), time.Date(2027, 1, 2, 3, 4, 5, 0, time.UTC), "https://example.com/docs")
+	want := http.Header{
+		"Deprecation": {"@1700000000"},
+		"Sunset":      {"Sat, 02 Jan 2027 03:04:05 GMT"},
+		"Link":        {`<https://example.com/docs>; rel="deprecation"`},
+	}
+	if !reflect.DeepEqual(w.Header(), want) {
+		t.Errorf("got headers %v, want %v", w.Header(), want)
+	}
+
+	w = httptest.NewRecorder()
+	Deprecate(w, time.Time{}, time.Time{}, "")
+	if len(w.Header()) != 0 {
+		t.Errorf("zero times set headers %v", w.Header())
+	}
+}
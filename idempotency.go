@@ -0,0 +1,153 @@
+package spirytus
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrKeyInUse is returned by IdempotencyStore.Begin when another request
+// with the same key is still being handled.
+var ErrKeyInUse = errors.New("spirytus: idempotency key in use")
+
+// An IdempotencyStore persists the responses recorded by the Idempotency
+// middleware. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Begin reserves key for a request about to be handled. If a response
+	// has already been recorded for key it is returned instead and the key
+	// is not reserved. If another request holds the key, Begin returns
+	// ErrKeyInUse.
+	Begin(key string) (*RecordedResponse, error)
+
+	// Finish releases a key reserved by Begin, recording resp for it.
+	// If resp is nil the key is released without recording anything so a
+	// later request may retry.
+	Finish(key string, resp *RecordedResponse)
+}
+
+// Idempotency returns a middleware that makes requests carrying an
+// Idempotency-Key header safe to retry. The first request with a given key is
+// handled as usual and its response recorded in store; later requests with
+// the same key receive the recorded response without invoking the handler.
+// A request arriving while another with the same key is in progress is
+// rejected with 409 Conflict.
+//
+// Keys are scoped to the method and path of the request, so the same key
+// sent to two endpoints names two unrelated requests; clients must still not
+// rely on reusing a key across endpoints. If scope is not nil, its result is
+// added to the scope as well, typically the identity of the user, so that
+// one user cannot replay another's response by guessing a key.
+//
+// Server error responses are not recorded, so the client may retry them.
+// Requests without the header are passed through untouched.
+func Idempotency(store IdempotencyStore, scope func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key := req.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			var s string
+			if scope != nil {
+				s = scope(req)
+			}
+			key = req.Method + "\x00" + req.URL.Path + "\x00" + s + "\x00" + key
+
+			resp, err := store.Begin(key)
+			if errors.Is(err, ErrKeyInUse) {
+				jsonError(w, http.StatusConflict, "Request with this idempotency key is in progress")
+				return
+			} else if err != nil {
+				jsonError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if resp != nil {
+				resp.Replay(w)
+				return
+			}
+
+			// Release the key if the handler panics.
+			var recorded *RecordedResponse
+			defer func() { store.Finish(key, recorded) }()
+
+			rec := newResponseRecorder()
+			next.ServeHTTP(rec, req)
+			resp = rec.response()
+			if !IsServerError(resp.Code) {
+				recorded = resp
+			}
+			resp.Replay(w)
+		})
+	}
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore that forgets
+// recorded responses after a fixed time.
+type MemoryIdempotencyStore struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	resp    *RecordedResponse // nil while the request is in progress
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns a store that keeps responses for ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// SetClock makes the store tell the time with c instead of the system clock,
+// so that tests can control expiry. It must be called before the store is
+// used.
+func (s *MemoryIdempotencyStore) SetClock(c Clock) {
+	s.clock = c
+}
+
+func (s *MemoryIdempotencyStore) Begin(key string) (*RecordedResponse, error) {
+	now := clockNow(s.clock)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		if e.resp == nil {
+			return nil, ErrKeyInUse
+		}
+		if now.Before(e.expires) {
+			return e.resp, nil
+		}
+	}
+	s.entries[key] = &idempotencyEntry{}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Finish(key string, resp *RecordedResponse) {
+	now := clockNow(s.clock)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resp == nil {
+		delete(s.entries, key)
+	} else {
+		s.entries[key] = &idempotencyEntry{resp, now.Add(s.ttl)}
+	}
+
+	// Drop expired responses at most once per ttl.
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if e.resp != nil && !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
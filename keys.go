@@ -0,0 +1,129 @@
+package spirytus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONRequestKeys reads the body of req in to v like JSONRequest, but first
+// rewrites every object key in the document, at any depth, with transform.
+// This lets a payload with inconsistently cased keys be decoded in to a struct
+// with consistently tagged fields:
+//
+//	err := spirytus.JSONRequestKeys(req, &v, spirytus.SnakeToCamel)
+//
+// The document is rewritten token by token, keeping the order of its keys, so
+// if two keys of an object transform to the same one, the value of the later
+// one in the document wins, as it would for duplicate keys in JSONRequest.
+func JSONRequestKeys(req *http.Request, v interface{}, transform func(string) string) error {
+	dec := json.NewDecoder(req.Body)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return decodeError(err)
+	}
+	var buf bytes.Buffer
+	if err := transformKeys(dec, &buf, tok, transform); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: data after top-level value", ErrMalformedJSON)
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+// transformKeys writes the value starting with tok to buf, reading the rest
+// of it from dec and rewriting object keys with transform.
+func transformKeys(dec *json.Decoder, buf *bytes.Buffer, tok json.Token, transform func(string) string) error {
+	next := func() (json.Token, error) {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return tok, decodeError(err)
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		b, err := json.Marshal(tok)
+		buf.Write(b)
+		return err
+	}
+	buf.WriteByte(byte(delim))
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		tok, err := next()
+		if err != nil {
+			return err
+		}
+		if delim == '{' {
+			b, err := json.Marshal(transform(tok.(string)))
+			if err != nil {
+				return err
+			}
+			buf.Write(b)
+			buf.WriteByte(':')
+			if tok, err = next(); err != nil {
+				return err
+			}
+		}
+		if err := transformKeys(dec, buf, tok, transform); err != nil {
+			return err
+		}
+	}
+	// Copy the closing delimiter.
+	end, err := next()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(byte(end.(json.Delim)))
+	return nil
+}
+
+// SnakeToCamel converts a snake_case key to camelCase, so "user_id" becomes
+// "userId". Keys without underscores are returned unchanged.
+func SnakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	if len(parts) == 1 {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, p := range parts[1:] {
+		r, size := utf8.DecodeRuneInString(p)
+		if size == 0 {
+			continue
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(p[size:])
+	}
+	return b.String()
+}
+
+// PascalToCamel converts a PascalCase key to camelCase by lowercasing its
+// leading capital, or its leading run of capitals if the key starts with an
+// initialism, so "UserName" becomes "userName", "HTTPServer" becomes
+// "httpServer" and "ID" becomes "id".
+func PascalToCamel(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		// The last capital starts the next word.
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,79 @@
+package spirytus
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRequestKeys(t *testing.T) {
+	tests := []struct {
+		body string
+		want map[string]interface{}
+	}{
+		{
+			`{"user_id":1,"home_address":{"zip_code":"123","geo_point":[{"lat_deg":1.5}]},"tags":["a_b",null,true]}`,
+			map[string]interface{}{
+				"userId": 1.0,
+				"homeAddress": map[string]interface{}{
+					"zipCode":  "123",
+					"geoPoint": []interface{}{map[string]interface{}{"latDeg": 1.5}},
+				},
+				"tags": []interface{}{"a_b", nil, true},
+			},
+		},
+		{`{"user_id":1,"userId":2}`, map[string]interface{}{"userId": 2.0}},
+		{`{"userId":2,"user_id":1}`, map[string]interface{}{"userId": 1.0}},
+		{`{"a":[{"b_c":1,"bC":2},{"bC":3,"b_c":4}]}`, map[string]interface{}{
+			"a": []interface{}{map[string]interface{}{"bC": 2.0}, map[string]interface{}{"bC": 4.0}},
+		}},
+		{`{"x_y":"<&>","e":{}, "l":[]}`, map[string]interface{}{"xY": "<&>", "e": map[string]interface{}{}, "l": []interface{}{}}},
+	}
+	for _, tt := range tests {
+		// Repeat to catch any dependence on map iteration order.
+		for i := 0; i < 20; i++ {
+			var got map[string]interface{}
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if err := JSONRequestKeys(req, &got, SnakeToCamel); err != nil {
+				t.Fatalf("%s: %v", tt.body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("%s: got %v, want %v", tt.body, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestJSONRequestKeysErrors(t *testing.T) {
+	tests := []struct {
+		body string
+		err  error
+	}{
+		{``, ErrEmptyBody},
+		{`{"a":`, ErrMalformedJSON},
+		{`{"a":1,}`, ErrMalformedJSON},
+		{`{"a":1}{}`, ErrMalformedJSON},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if err := JSONRequestKeys(req, &v, SnakeToCamel); !errors.Is(err, tt.err) {
+			t.Errorf("%q: got error %v, want %v", tt.body, err, tt.err)
+		}
+	}
+}
+
+func TestCaseTransforms(t *testing.T) {
+	for in, want := range map[string]string{"user_id": "userId", "a__b": "aB", "plain": "plain"} {
+		if got := SnakeToCamel(in); got != want {
+			t.Errorf("SnakeToCamel(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{"UserName": "userName", "HTTPServer": "httpServer", "ID": "id", "x": "x"} {
+		if got := PascalToCamel(in); got != want {
+			t.Errorf("PascalToCamel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package spirytus
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// PreferredLanguage returns the tag from supported that best matches the
+// Accept-Language header of req, taking weights in to account and following
+// the matching rules of golang.org/x/text/language, so that for example
+// "en-GB" is matched by a supported "en". If the header is missing or nothing
+// matches, the first supported tag is returned.
+//
+// PreferredLanguage builds a new matcher for every call; NegotiateLanguage
+// builds one up front.
+func PreferredLanguage(req *http.Request, supported []language.Tag) language.Tag {
+	if len(supported) == 0 {
+		return language.Und
+	}
+	return matchLanguage(language.NewMatcher(supported), supported, req)
+}
+
+func matchLanguage(m language.Matcher, supported []language.Tag, req *http.Request) language.Tag {
+	accept, _, err := language.ParseAcceptLanguage(req.Header.Get("Accept-Language"))
+	if err != nil || len(accept) == 0 {
+		return supported[0]
+	}
+	_, i, _ := m.Match(accept...)
+	return supported[i]
+}
+
+// NegotiateLanguage returns a middleware that chooses the language of the
+// response as PreferredLanguage does and makes it available to handlers
+// through LanguageFromContext, for example to localize error messages.
+// It panics if supported is empty.
+func NegotiateLanguage(supported ...language.Tag) Middleware {
+	if len(supported) == 0 {
+		panic("spirytus: NegotiateLanguage needs at least one supported language")
+	}
+	m := language.NewMatcher(supported)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			tag := matchLanguage(m, supported, req)
+			ctx := context.WithValue(req.Context(), languageKey, tag)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// LanguageFromContext returns the language chosen by NegotiateLanguage.
+func LanguageFromContext(ctx context.Context) (language.Tag, bool) {
+	tag, ok := ctx.Value(languageKey).(language.Tag)
+	return tag, ok
+}
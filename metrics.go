@@ -0,0 +1,25 @@
+package spirytus
+
+import (
+	"net/http"
+)
+
+// SizeMetrics returns a middleware that measures the size of request and
+// response bodies. Once the handler returns, sink is called with the number
+// of bytes the handler read from the request body and the number it wrote to
+// the response. Bodies are counted as they stream, so chunked requests and
+// responses are unaffected, and a handler that never reads the request body
+// reports zero for it.
+func SizeMetrics(sink func(reqBytes, respBytes int64)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body := &countingBody{ReadCloser: req.Body}
+			if req.Body != nil {
+				req.Body = body
+			}
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() { sink(body.n, rw.written) }()
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package spirytus
+
+import (
+	"net/http"
+)
+
+// A Middleware wraps an http.Handler to add behaviour before or after it runs.
+type Middleware func(http.Handler) http.Handler
+
+// RequireBody rejects POST, PUT and PATCH requests without a body with 400
+// Bad Request before they reach next, rather than leaving the handler to fail
+// decoding an empty body. A chunked body of unknown length is let through.
+// Requests with other methods are passed through untouched.
+func RequireBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "POST", "PUT", "PATCH":
+			if req.ContentLength == 0 || req.Body == nil || req.Body == http.NoBody {
+				jsonError(w, http.StatusBadRequest, "Request body required")
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,32 @@
+package spirytus
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+)
+
+// MultipartRequest parses a multipart/form-data request body, keeping up to
+// maxMemory bytes of file parts in memory and storing the rest in temporary
+// files, as http.Request.ParseMultipartForm does. In addition the whole body
+// is limited to maxTotal bytes, so a client cannot exhaust memory or disk.
+//
+// ErrUnsupportedMediaType is returned if the request is not multipart, and an
+// error wrapping ErrBodyTooLarge if it exceeds either limit.
+func MultipartRequest(req *http.Request, maxMemory, maxTotal int64) (*multipart.Form, error) {
+	if !hasMediaType(req, "multipart/form-data") {
+		return nil, ErrUnsupportedMediaType
+	}
+	req.Body = http.MaxBytesReader(nil, req.Body, maxTotal)
+	err := req.ParseMultipartForm(maxMemory)
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr), errors.Is(err, multipart.ErrMessageTooLarge):
+		return nil, errors.Join(ErrBodyTooLarge, err)
+	case errors.Is(err, http.ErrNotMultipart):
+		return nil, ErrUnsupportedMediaType
+	case err != nil:
+		return nil, err
+	}
+	return req.MultipartForm, nil
+}
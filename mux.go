@@ -0,0 +1,149 @@
+package spirytus
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Mux dispatches requests to Resources by exact match on the request path,
+// or to handlers registered for a prefix of it.
+// The zero value is an empty Mux ready to use.
+type Mux struct {
+	resources map[string]*Resource
+	prefixes  []prefixHandler // longest prefix first
+	notFound  http.Handler
+}
+
+type prefixHandler struct {
+	prefix  string
+	handler http.Handler
+}
+
+// Handle registers the resource for the given path, replacing any resource
+// previously registered there.
+func (m *Mux) Handle(path string, r *Resource) {
+	if m.resources == nil {
+		m.resources = make(map[string]*Resource)
+	}
+	m.resources[path] = r
+}
+
+// Resource returns the resource registered for path. If there is none, a new
+// empty resource is registered and returned.
+func (m *Mux) Resource(path string) *Resource {
+	if r, ok := m.resources[path]; ok && r != nil {
+		return r
+	}
+	r := new(Resource)
+	m.Handle(path, r)
+	return r
+}
+
+// HandlePrefix registers h for every request whose path starts with prefix,
+// replacing any handler previously registered for the same prefix. A resource
+// registered for the exact path takes precedence over any prefix, and
+// otherwise the longest matching prefix wins. The matched prefix is available
+// to the handler through MatchedPrefix.
+func (m *Mux) HandlePrefix(prefix string, h http.Handler) {
+	for i, p := range m.prefixes {
+		if p.prefix == prefix {
+			m.prefixes[i].handler = h
+			return
+		}
+	}
+	i := 0
+	for i < len(m.prefixes) && len(m.prefixes[i].prefix) >= len(prefix) {
+		i++
+	}
+	m.prefixes = append(m.prefixes, prefixHandler{})
+	copy(m.prefixes[i+1:], m.prefixes[i:])
+	m.prefixes[i] = prefixHandler{prefix, h}
+}
+
+// MatchedPrefix returns the prefix that was matched by a Mux to dispatch the
+// request to a handler registered with HandlePrefix. The boolean is false if
+// the request was not dispatched by prefix.
+func MatchedPrefix(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(prefixKey).(string)
+	return p, ok
+}
+
+// NotFound sets the handler invoked for requests whose path matches neither
+// a registered resource with handlers nor a prefix. By default such requests
+// receive a plain 404 response. A request whose path matches a resource that
+// does not handle its method still receives the resource's 405 response.
+func (m *Mux) NotFound(h http.Handler) {
+	m.notFound = h
+}
+
+// Derive returns an independent copy of the mux. Every resource is cloned,
+// so resources and handlers may be added to or replaced in the derived mux
+// without affecting m. This makes it possible to build a versioned API from
+// a common base:
+//
+//	v2 := v1.Derive()
+//	v2.Resource("/users").Handle("GET", listUsersV2)
+func (m *Mux) Derive() *Mux {
+	d := &Mux{
+		prefixes: append([]prefixHandler(nil), m.prefixes...),
+		notFound: m.notFound,
+	}
+	for path, r := range m.resources {
+		d.Handle(path, r.Clone())
+	}
+	return d
+}
+
+// RouteInfo describes a route registered with a Mux.
+type RouteInfo struct {
+	Path    string
+	Methods []string // nil for prefix routes
+	Prefix  bool     // registered with HandlePrefix
+}
+
+// Routes returns the routes registered with the mux, sorted by path, for
+// debugging or documenting a service. An exact route sorts before a prefix
+// route for the same path. Resources without handlers are left out.
+func (m *Mux) Routes() []RouteInfo {
+	var routes []RouteInfo
+	for path, r := range m.resources {
+		if r != nil && len(r.methods) > 0 {
+			routes = append(routes, RouteInfo{Path: path, Methods: r.Methods()})
+		}
+	}
+	for _, p := range m.prefixes {
+		routes = append(routes, RouteInfo{Path: p.prefix, Prefix: true})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return !routes[i].Prefix && routes[j].Prefix
+	})
+	return routes
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// A resource without handlers does not count as a match.
+	r := m.resources[req.URL.Path]
+	if r != nil && len(r.methods) == 0 {
+		r = nil
+	}
+	if r == nil {
+		for _, p := range m.prefixes {
+			if strings.HasPrefix(req.URL.Path, p.prefix) {
+				ctx := context.WithValue(req.Context(), prefixKey, p.prefix)
+				p.handler.ServeHTTP(w, req.WithContext(ctx))
+				return
+			}
+		}
+	}
+	if r == nil && m.notFound != nil {
+		m.notFound.ServeHTTP(w, req)
+		return
+	}
+	// A nil resource responds with 404.
+	r.ServeHTTP(w, req)
+}
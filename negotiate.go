@@ -0,0 +1,233 @@
+package spirytus
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptSpec is a single element of an Accept-style header, such as
+// "text/html;q=0.8".
+type acceptSpec struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses every value of the named header in h as a
+// comma-separated list of elements with optional q weights. Values are
+// lowercased and elements with malformed weights are dropped.
+func parseAccept(h http.Header, name string) []acceptSpec {
+	var specs []acceptSpec
+	for _, line := range h.Values(name) {
+		for _, elem := range strings.Split(line, ",") {
+			params := strings.Split(elem, ";")
+			spec := acceptSpec{strings.ToLower(strings.TrimSpace(params[0])), 1}
+			if spec.value == "" {
+				continue
+			}
+			valid := true
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				if strings.ToLower(strings.TrimSpace(k)) != "q" {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil || q < 0 || q > 1 {
+					valid = false
+					break
+				}
+				spec.q = q
+			}
+			if valid {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	return specs
+}
+
+// acceptsMediaType reports whether mediaType is acceptable according to
+// specs. The weight of the most specific matching range applies, so
+// "text/html;q=0, text/*" rejects text/html while accepting text/plain.
+func acceptsMediaType(specs []acceptSpec, mediaType string) bool {
+	mediaType, _, _ = strings.Cut(strings.ToLower(mediaType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	best, q := 0, 0.0
+	for _, s := range specs {
+		var specificity int
+		switch s.value {
+		case mediaType:
+			specificity = 3
+		case typ + "/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		default:
+			continue
+		}
+		if specificity > best {
+			best, q = specificity, s.q
+		}
+	}
+	return q > 0
+}
+
+// RequireAccept returns a middleware that rejects requests with 406 Not
+// Acceptable unless their Accept header allows at least one of the given
+// media types. A request without an Accept header accepts anything.
+func RequireAccept(mediaTypes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(req.Header.Values("Accept")) == 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+			specs := parseAccept(req.Header, "Accept")
+			for _, t := range mediaTypes {
+				if acceptsMediaType(specs, t) {
+					next.ServeHTTP(w, req)
+					return
+				}
+			}
+			jsonError(w, http.StatusNotAcceptable, "Not acceptable")
+		})
+	}
+}
+
+// RequireContentType returns a middleware that rejects POST, PUT and PATCH
+// requests with 415 Unsupported Media Type unless their Content-Type is one
+// of the given media types. Parameters such as charset are ignored, and if
+// application/json is among the types, any type with a +json suffix such as
+// application/merge-patch+json matches it too. Requests with other methods
+// are passed through untouched.
+func RequireContentType(types ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case "POST", "PUT", "PATCH":
+			default:
+				next.ServeHTTP(w, req)
+				return
+			}
+			if t, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil {
+				for _, want := range types {
+					want = strings.ToLower(want)
+					if t == want || want == "application/json" && strings.HasSuffix(t, "+json") {
+						next.ServeHTTP(w, req)
+						return
+					}
+				}
+			}
+			jsonError(w, http.StatusUnsupportedMediaType, "Unsupported media type")
+		})
+	}
+}
+
+// AllowedEncodings returns a middleware that rejects requests with 415
+// Unsupported Media Type if their body has a Content-Encoding other than the
+// given ones, before the handler tries to read it. Every coding applied to
+// the body must be allowed. Unencoded bodies are always accepted, so with no
+// encodings only identity is allowed.
+func AllowedEncodings(encodings ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			for _, v := range req.Header.Values("Content-Encoding") {
+				for _, coding := range strings.Split(v, ",") {
+					if !allowedEncoding(strings.TrimSpace(coding), encodings) {
+						jsonError(w, http.StatusUnsupportedMediaType, "Unsupported content encoding")
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func allowedEncoding(coding string, encodings []string) bool {
+	if coding == "" || strings.EqualFold(coding, "identity") {
+		return true
+	}
+	for _, e := range encodings {
+		if strings.EqualFold(coding, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodingQ returns the weight given to coding by the Accept-Encoding specs,
+// and whether it is mentioned explicitly or through "*".
+func encodingQ(specs []acceptSpec, coding string) (float64, bool) {
+	wildcard, haveWildcard := 0.0, false
+	for _, s := range specs {
+		v := s.value
+		if v == "x-gzip" {
+			v = "gzip"
+		}
+		if v == coding {
+			return s.q, true
+		}
+		if v == "*" {
+			wildcard, haveWildcard = s.q, true
+		}
+	}
+	return wildcard, haveWildcard
+}
+
+// AcceptsEncoding reports whether the client accepts a response body with
+// the given content coding, such as "gzip", according to the Accept-Encoding
+// header of req and the rules of RFC 7231. A coding is acceptable if it, or
+// failing that "*", is listed with a non-zero weight. A request without the
+// header accepts any coding. The identity coding is acceptable unless it is
+// excluded explicitly with "identity;q=0" or through "*;q=0".
+func AcceptsEncoding(req *http.Request, coding string) bool {
+	if len(req.Header.Values("Accept-Encoding")) == 0 {
+		return true
+	}
+	coding = strings.ToLower(coding)
+	if coding == "x-gzip" {
+		coding = "gzip"
+	}
+	q, ok := encodingQ(parseAccept(req.Header, "Accept-Encoding"), coding)
+	if !ok {
+		return coding == "identity"
+	}
+	return q > 0
+}
+
+// NegotiateEncoding chooses the content coding for a response from the
+// codings the server can offer, in order of the server's preference, and
+// "identity". The acceptable coding with the highest weight in the
+// Accept-Encoding header of req is returned, with ties going to the server's
+// preference and identity coming last. The boolean is false if no coding,
+// not even identity, is acceptable, in which case the server should respond
+// with 406 Not Acceptable.
+func NegotiateEncoding(req *http.Request, offers ...string) (string, bool) {
+	if len(req.Header.Values("Accept-Encoding")) == 0 {
+		return "identity", true
+	}
+	specs := parseAccept(req.Header, "Accept-Encoding")
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		coding := strings.ToLower(offer)
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		if q, _ := encodingQ(specs, coding); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	q, ok := encodingQ(specs, "identity")
+	if !ok {
+		// Identity is implicitly acceptable but least preferred.
+		q = 0.001
+	}
+	if q > bestQ {
+		best, bestQ = "identity", q
+	}
+	return best, bestQ > 0
+}
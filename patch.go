@@ -0,0 +1,454 @@
+package spirytus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is wrapped by errors returned from JSONPatchRequest
+	// when the patch is malformed or cannot be applied to the document,
+	// for example because it refers to a member that does not exist.
+	// Handlers typically respond with 422 Unprocessable Entity.
+	ErrInvalidPatch = errors.New("spirytus: invalid patch")
+
+	// ErrPatchTestFailed is wrapped by errors returned from
+	// JSONPatchRequest when a "test" operation does not hold.
+	// Handlers typically respond with 409 Conflict.
+	ErrPatchTestFailed = errors.New("spirytus: patch test failed")
+)
+
+// hasMediaType reports whether the Content-Type of req is mediaType,
+// ignoring any parameters.
+func hasMediaType(req *http.Request, mediaType string) bool {
+	t, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && t == mediaType
+}
+
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatchRequest applies the JSON Patch (RFC 6902) in the body of req to
+// target, which must be a pointer to a value that can be encoded to and
+// decoded from JSON. The request must have the media type
+// application/json-patch+json, otherwise ErrUnsupportedMediaType is returned.
+//
+// The patch is applied to the JSON encoding of target and only the result is
+// decoded back in to it, so a patch with a failing operation leaves target
+// untouched. Decoding follows the rules of json.Unmarshal, so a member removed
+// by the patch leaves the corresponding struct field at its previous value;
+// use a map target if removals must be observed.
+//
+// If the patched document does not fit target, for example because an
+// operation replaced a number with a string, target is restored by decoding
+// its original encoding in to it again and an error wrapping ErrInvalidPatch
+// is returned. Fields missing from the original encoding, such as empty
+// fields tagged omitempty, cannot be restored this way and may keep values
+// from the patched document.
+//
+// Errors for malformed or inapplicable patches wrap ErrInvalidPatch and
+// errors for failed "test" operations wrap ErrPatchTestFailed.
+func JSONPatchRequest(req *http.Request, target interface{}) error {
+	if !hasMediaType(req, "application/json-patch+json") {
+		return ErrUnsupportedMediaType
+	}
+	var ops []patchOp
+	dec := json.NewDecoder(req.Body)
+	if err := decodeError(dec.Decode(&ops)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: data after patch document", ErrInvalidPatch)
+	}
+
+	orig, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	doc, err := decodeNumber(orig)
+	if err != nil {
+		return err
+	}
+	for i, op := range ops {
+		if doc, err = op.apply(doc); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		json.Unmarshal(orig, target)
+		return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+// MergePatchRequest applies the JSON Merge Patch (RFC 7396) in the body of
+// req to the JSON encoding of current and returns the merged document, which
+// the handler can decode in to a fresh value of its own type. The request must
+// have the media type application/merge-patch+json, otherwise
+// ErrUnsupportedMediaType is returned. A patch that is not valid JSON, or is
+// followed by anything but white space, results in an error wrapping
+// ErrInvalidPatch.
+//
+// Following the RFC, a null member in the patch removes the member from the
+// document, objects are merged recursively and any other value, including an
+// array, replaces the original wholesale. A patch that is not an object
+// replaces the entire document.
+func MergePatchRequest(req *http.Request, current interface{}) ([]byte, error) {
+	if !hasMediaType(req, "application/merge-patch+json") {
+		return nil, ErrUnsupportedMediaType
+	}
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := decodeNumber(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	if b, err = json.Marshal(current); err != nil {
+		return nil, err
+	}
+	doc, err := decodeNumber(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(doc, patch))
+}
+
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+		} else {
+			d[k] = mergePatch(d[k], v)
+		}
+	}
+	return d
+}
+
+// decodeNumber decodes JSON in to a generic value, keeping numbers as
+// json.Number so that they survive a round trip unchanged. Anything but white
+// space after the value is an error wrapping ErrMalformedJSON.
+func decodeNumber(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: data after top-level value", ErrMalformedJSON)
+	}
+	return v, nil
+}
+
+func (op *patchOp) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var from []string
+	switch op.Op {
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	}
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		if value, err = decodeNumber(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return patchAdd(doc, path, value)
+	case "remove":
+		return patchRemove(doc, path)
+	case "replace":
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		return patchRemoveAdd(doc, path, value)
+	case "move":
+		if len(from) < len(path) && isPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move %q in to itself", ErrInvalidPatch, *op.From)
+		}
+		if value, err = pointerGet(doc, from); err != nil {
+			return nil, err
+		}
+		if len(from) == len(path) && isPrefix(from, path) {
+			return doc, nil
+		}
+		if doc, err = patchRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, value)
+	case "copy":
+		if value, err = pointerGet(doc, from); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, deepCopy(value))
+	case "test":
+		current, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, value) {
+			return nil, fmt.Errorf("%w: %q", ErrPatchTestFailed, *op.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) in to its unescaped
+// reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w: bad pointer %q", ErrInvalidPatch, p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses token as an index in to an array of length n. If end
+// is true the index may equal n, and "-" refers to it.
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || token != strconv.Itoa(i) {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPatch, token)
+	}
+	if i > n || i == n && !end {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrInvalidPatch, i)
+	}
+	return i, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch n := doc.(type) {
+		case map[string]interface{}:
+			v, ok := n[t]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = n[i]
+		default:
+			return nil, fmt.Errorf("%w: cannot index scalar with %q", ErrInvalidPatch, t)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate replaces the container holding the last token of path with
+// the result of calling fn on it, returning the updated document.
+func pointerUpdate(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := pointerGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = pointerUpdate(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch n := doc.(type) {
+	case map[string]interface{}:
+		n[path[0]] = child
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(n), false)
+		n[i] = child
+	}
+	return doc, nil
+}
+
+func patchAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, t string) (interface{}, error) {
+		switch n := parent.(type) {
+		case map[string]interface{}:
+			n[t] = value
+			return n, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(n), true)
+			if err != nil {
+				return nil, err
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		return nil, fmt.Errorf("%w: cannot add member %q to scalar", ErrInvalidPatch, t)
+	})
+}
+
+func patchRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, t string) (interface{}, error) {
+		switch n := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := n[t]; !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, t)
+			}
+			delete(n, t)
+			return n, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:i], n[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: cannot remove member %q from scalar", ErrInvalidPatch, t)
+	})
+}
+
+// patchRemoveAdd replaces the existing value at path with value.
+func patchRemoveAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return pointerUpdate(doc, path, func(parent interface{}, t string) (interface{}, error) {
+		switch n := parent.(type) {
+		case map[string]interface{}:
+			n[t] = value
+			return n, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = value
+			return n, nil
+		}
+		return nil, fmt.Errorf("%w: cannot replace member %q of scalar", ErrInvalidPatch, t)
+	})
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, e := range n {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(n))
+		for i, e := range n {
+			s[i] = deepCopy(e)
+		}
+		return s
+	}
+	return v
+}
+
+// jsonEqual reports whether two generic JSON values are equal. Numbers are
+// compared exactly by value, so 1 and 1.0 are equal but 9007199254740993 and
+// 9007199254740992 are not.
+func jsonEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		rx, okx := new(big.Rat).SetString(x.String())
+		ry, oky := new(big.Rat).SetString(y.String())
+		return okx && oky && rx.Cmp(ry) == 0
+	}
+	return a == b
+}
@@ -0,0 +1,137 @@
+package spirytus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func patchRequest(mediaType, body string) *http.Request {
+	req := httptest.NewRequest("PATCH", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", mediaType)
+	return req
+}
+
+func TestJSONPatchRequest(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+		err              error
+	}{
+		{`{"a":1}`, `[{"op":"add","path":"/b","value":[1,2]}]`, `{"a":1,"b":[1,2]}`, nil},
+		{`{"a":[1,2]}`, `[{"op":"add","path":"/a/1","value":9},{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,9,2,3]}`, nil},
+		{`{"a":[1,2]}`, `[{"op":"remove","path":"/a/0"}]`, `{"a":[2]}`, nil},
+		{`{"a":{"b":1}}`, `[{"op":"move","from":"/a/b","path":"/c"}]`, `{"a":{},"c":1}`, nil},
+		{`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, nil},
+		{`{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/m~0n","value":null}]`, `{"m~n":null}`, nil},
+		{`{"a":1.0}`, `[{"op":"test","path":"/a","value":1}]`, `{"a":1}`, nil},
+		{`{"a":9007199254740993}`, `[{"op":"test","path":"/a","value":9007199254740993}]`, `{"a":9007199254740993}`, nil},
+		{`{"a":9007199254740993}`, `[{"op":"test","path":"/a","value":9007199254740992}]`, ``, ErrPatchTestFailed},
+		{`{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ``, ErrPatchTestFailed},
+		{`{"a":1}`, `[{"op":"remove","path":"/x"}]`, ``, ErrInvalidPatch},
+		{`{"a":1}`, `[{"op":"nope","path":"/a"}]`, ``, ErrInvalidPatch},
+		{`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ``, ErrInvalidPatch},
+		{`{"a":[1]}`, `[{"op":"add","path":"/a/01","value":1}]`, ``, ErrInvalidPatch},
+		{`{"a":1}`, `[{"op":"remove","path":"/a"}] garbage`, ``, ErrInvalidPatch},
+		{`{"a":1}`, `[{"op":"remove","path":"/a"}][]`, ``, ErrInvalidPatch},
+		{`{"a":1}`, "[{\"op\":\"remove\",\"path\":\"/a\"}]\n", `{}`, nil},
+	}
+	for _, tt := range tests {
+		doc := json.RawMessage(tt.doc)
+		err := JSONPatchRequest(patchRequest("application/json-patch+json", tt.patch), &doc)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: got error %v, want %v", tt.patch, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.patch, err)
+			continue
+		}
+		if !jsonBytesEqual(doc, []byte(tt.want)) {
+			t.Errorf("%s: got %s, want %s", tt.patch, doc, tt.want)
+		}
+	}
+}
+
+func TestJSONPatchRequestTypeMismatch(t *testing.T) {
+	type doc struct {
+		A int
+		B string
+	}
+	target := doc{A: 1, B: "orig"}
+	patch := `[{"op":"replace","path":"/A","value":"str"},{"op":"replace","path":"/B","value":"changed"}]`
+	err := JSONPatchRequest(patchRequest("application/json-patch+json", patch), &target)
+	if !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("got error %v, want ErrInvalidPatch", err)
+	}
+	if want := (doc{A: 1, B: "orig"}); target != want {
+		t.Errorf("target = %+v, want %+v", target, want)
+	}
+}
+
+func TestJSONPatchRequestMediaType(t *testing.T) {
+	var doc interface{}
+	err := JSONPatchRequest(patchRequest("application/json", `[]`), &doc)
+	if err != ErrUnsupportedMediaType {
+		t.Errorf("got error %v, want ErrUnsupportedMediaType", err)
+	}
+}
+
+// jsonBytesEqual reports whether a and b encode equal JSON values.
+func jsonBytesEqual(a, b []byte) bool {
+	va, erra := decodeNumber(a)
+	vb, errb := decodeNumber(b)
+	return erra == nil && errb == nil && jsonEqual(va, vb)
+}
+
+// TestMergePatchRequest runs the examples from RFC 7396, Appendix A.
+func TestMergePatchRequest(t *testing.T) {
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatchRequest(patchRequest("application/merge-patch+json", tt.patch), json.RawMessage(tt.doc))
+		if err != nil {
+			t.Errorf("%s + %s: %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if !jsonBytesEqual(got, []byte(tt.want)) {
+			t.Errorf("%s + %s = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatchRequestInvalid(t *testing.T) {
+	_, err := MergePatchRequest(patchRequest("application/merge-patch+json", `{"a":`), struct{}{})
+	if !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("got error %v, want ErrInvalidPatch", err)
+	}
+	for _, patch := range []string{`{"a":1}{}`, `{"a":1} x`, `null null`} {
+		_, err = MergePatchRequest(patchRequest("application/merge-patch+json", patch), struct{}{})
+		if !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%s: got error %v, want ErrInvalidPatch", patch, err)
+		}
+	}
+	_, err = MergePatchRequest(patchRequest("application/json", `{}`), struct{}{})
+	if err != ErrUnsupportedMediaType {
+		t.Errorf("got error %v, want ErrUnsupportedMediaType", err)
+	}
+}
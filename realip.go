@@ -0,0 +1,90 @@
+package spirytus
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP returns a middleware that determines the IP address of the client
+// behind any trusted proxies and makes it available through ClientIP.
+//
+// If the direct peer is not within one of the trusted prefixes, its address is
+// the client's and forwarding headers are ignored, so they cannot be spoofed.
+// Otherwise the X-Forwarded-For header is walked from right to left, skipping
+// addresses of trusted proxies, and the first untrusted address is taken as
+// the client. Without X-Forwarded-For, a valid X-Real-IP header is used.
+func RealIP(trustedProxies []netip.Prefix) Middleware {
+	trusted := func(addr netip.Addr) bool {
+		for _, p := range trustedProxies {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ip, ok := parseIP(req.RemoteAddr)
+			if !ok {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if trusted(ip) {
+				ip = forwardedIP(req.Header, ip, trusted)
+			}
+			ctx := context.WithValue(req.Context(), clientIPKey, ip)
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// forwardedIP returns the client address given by the forwarding headers of
+// a request received from the trusted proxy peer.
+func forwardedIP(h http.Header, peer netip.Addr, trusted func(netip.Addr) bool) netip.Addr {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip, ok := parseIP(h.Get("X-Real-IP")); ok {
+			return ip
+		}
+		return peer
+	}
+	ip := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseIP(hops[i])
+		if !ok {
+			// Everything to the left of a malformed entry is suspect,
+			// so settle for the last hop that could be verified.
+			break
+		}
+		ip = hop
+		if !trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// parseIP parses an IP address, optionally with a port.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	ip, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// ClientIP returns the client address determined by RealIP.
+func ClientIP(ctx context.Context) (netip.Addr, bool) {
+	ip, ok := ctx.Value(clientIPKey).(netip.Addr)
+	return ip, ok
+}
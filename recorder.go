@@ -0,0 +1,62 @@
+package spirytus
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// A RecordedResponse is a complete response captured from a handler so that
+// it can be replayed later.
+type RecordedResponse struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// Replay writes the recorded response to w.
+func (r *RecordedResponse) Replay(w http.ResponseWriter) {
+	h := w.Header()
+	for k, v := range r.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(r.Code)
+	w.Write(r.Body)
+}
+
+// responseRecorder is a ResponseWriter that buffers the response in memory
+// instead of sending it.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+// response returns the recorded response. A handler that wrote nothing
+// produces an empty 200 response, as it would have with net/http.
+func (r *responseRecorder) response() *RecordedResponse {
+	r.WriteHeader(http.StatusOK)
+	return &RecordedResponse{
+		Code:   r.code,
+		Header: r.header.Clone(),
+		Body:   append([]byte(nil), r.body.Bytes()...),
+	}
+}
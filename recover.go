@@ -0,0 +1,98 @@
+package spirytus
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover returns a middleware that recovers from panics in the handler and
+// responds with 500 Internal Server Error. If the handler had already started
+// writing its response, it can no longer be replaced, so Recover panics with
+// http.ErrAbortHandler instead to abort the connection and let the client see
+// that the response is incomplete. Before either, onPanic, if not nil, is
+// called with the request, the recovered value and the stack trace of the
+// panic, so that it can be reported to an error tracker. The callback cannot
+// prevent the response, and a panic in the callback itself is contained.
+//
+// Headers that the handler set to describe the response it meant to write,
+// such as Content-Length, ETag and Cache-Control, are removed so that they do
+// not apply to the error response. Other headers are left in place.
+//
+// A panic with a *PanicError, as raised by HardDeadline, is reported with the
+// value and stack it carries.
+//
+// A panic with http.ErrAbortHandler is not recovered, so that it can abort
+// the response as net/http intends.
+func Recover(onPanic func(req *http.Request, recovered interface{}, stack []byte)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				if onPanic != nil {
+					if pe, ok := p.(*PanicError); ok {
+						reportPanic(onPanic, req, pe.Value, pe.Stack)
+					} else {
+						reportPanic(onPanic, req, p, debug.Stack())
+					}
+				}
+				switch {
+				case rw.hijacked:
+					// The handler owns the connection.
+				case rw.code != 0:
+					panic(http.ErrAbortHandler)
+				default:
+					h := w.Header()
+					for _, name := range representationHeaders {
+						h.Del(name)
+					}
+					jsonError(w, http.StatusInternalServerError, "Internal server error")
+				}
+			}()
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// A PanicError carries a panic from one goroutine to another, along with the
+// stack of the goroutine where it happened, which would otherwise be lost.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// representationHeaders describe a response body and must not survive its
+// replacement with an error.
+var representationHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Range",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+}
+
+func reportPanic(onPanic func(*http.Request, interface{}, []byte), req *http.Request, p interface{}, stack []byte) {
+	defer func() { recover() }()
+	onPanic(req, p, stack)
+}
@@ -0,0 +1,87 @@
+package spirytus
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoverClearsHeaders(t *testing.T) {
+	h := Recover(nil)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "1234")
+		w.Header().Set("ETag", `"x"`)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("X-Request-ID", "abc")
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 500 {
+		t.Errorf("got status %d, want 500", w.Code)
+	}
+	for _, name := range []string{"Content-Length", "ETag", "Cache-Control"} {
+		if v := w.Header().Get(name); v != "" {
+			t.Errorf("%s = %q after panic", name, v)
+		}
+	}
+	if v := w.Header().Get("X-Request-ID"); v != "abc" {
+		t.Errorf("X-Request-ID = %q, want abc", v)
+	}
+}
+
+func TestRecoverAbortsPartialResponse(t *testing.T) {
+	reported := make(chan struct{}, 1)
+	srv := httptest.NewUnstartedServer(Recover(func(*http.Request, interface{}, []byte) {
+		reported <- struct{}{}
+	})(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		panic("boom")
+	})))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Error("reading the partial response succeeded, want an error")
+	}
+	select {
+	case <-reported:
+	default:
+		t.Error("panic not reported")
+	}
+}
+
+func stuckHandlerPanics() {
+	panic("boom")
+}
+
+func TestRecoverHardDeadlineStack(t *testing.T) {
+	var got interface{}
+	var stack []byte
+	h := Recover(func(req *http.Request, p interface{}, s []byte) {
+		got, stack = p, s
+	})(HardDeadline(time.Second)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		stuckHandlerPanics()
+	})))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 500 {
+		t.Errorf("got status %d, want 500", w.Code)
+	}
+	if got != "boom" {
+		t.Errorf("got panic value %v, want boom", got)
+	}
+	if !strings.Contains(string(stack), "stuckHandlerPanics") {
+		t.Errorf("stack does not show the handler:\n%s", stack)
+	}
+}
@@ -0,0 +1,17 @@
+package spirytus
+
+import (
+	"context"
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID id, so that it
+// can be included in logs and error responses.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by WithRequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
@@ -0,0 +1,268 @@
+package spirytus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrorResponse writes a JSON error response of the form
+// {"error": msg, "request_id": id} with the provided status code. The
+// request ID is taken from the context of req, as set by WithRequestID, and
+// omitted if there is none, so that clients can quote it to support.
+func ErrorResponse(w http.ResponseWriter, req *http.Request, code int, msg string) error {
+	id, _ := RequestIDFromContext(req.Context())
+	return JSONResponse(w, code, errorBody{msg, id})
+}
+
+// UpgradeRequired writes a 426 Upgrade Required response asking the client to
+// switch to one of the given protocols, such as "websocket". The Upgrade and
+// Connection headers are set and the body is a JSON error listing the
+// protocols. RFC 7231 requires at least one protocol; if none is given an
+// error is returned and nothing is written.
+func UpgradeRequired(w http.ResponseWriter, protocols ...string) error {
+	if len(protocols) == 0 {
+		return errors.New("spirytus: no protocols to upgrade to")
+	}
+	w.Header().Set("Upgrade", strings.Join(protocols, ", "))
+	w.Header().Set("Connection", "Upgrade")
+	return JSONResponse(w, http.StatusUpgradeRequired, struct {
+		Error   string   `json:"error"`
+		Upgrade []string `json:"upgrade"`
+	}{"Upgrade required", protocols})
+}
+
+// JSONResponseFields writes a JSON response like JSONResponse, but includes
+// only the named fields of value, for clients that request partial responses.
+// A field may name a nested member with a dotted path such as "user.name".
+// The mask applies to every element of an array, so it also works for lists
+// of objects. If fields is empty the whole value is written.
+//
+// To stay free of reflection, value is encoded to JSON and decoded again
+// before the mask is applied, so field names are the JSON member names and
+// the cost is roughly that of marshalling value twice.
+func JSONResponseFields(w http.ResponseWriter, code int, value interface{}, fields []string) error {
+	if len(fields) == 0 {
+		return JSONResponse(w, code, value)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	doc, err := decodeNumber(b)
+	if err != nil {
+		return err
+	}
+	mask := make(fieldMask)
+	for _, f := range fields {
+		mask.add(strings.Split(f, "."))
+	}
+	return JSONResponse(w, code, mask.apply(doc))
+}
+
+// A fieldMask selects members of a JSON object. A nil mask for a member
+// selects the whole member.
+type fieldMask map[string]fieldMask
+
+func (m fieldMask) add(path []string) {
+	sub, ok := m[path[0]]
+	if len(path) == 1 {
+		m[path[0]] = nil
+		return
+	}
+	if ok && sub == nil {
+		// The whole member is already selected.
+		return
+	}
+	if !ok {
+		sub = make(fieldMask)
+		m[path[0]] = sub
+	}
+	sub.add(path[1:])
+}
+
+func (m fieldMask) apply(doc interface{}) interface{} {
+	if m == nil {
+		return doc
+	}
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(m))
+		for k, sub := range m {
+			if e, ok := v[k]; ok {
+				out[k] = sub.apply(e)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = m.apply(e)
+		}
+		return out
+	}
+	return doc
+}
+
+// Created writes a 201 Created response with a Location header pointing to
+// the new resource and value as its JSON body. The location must be an
+// absolute URL or a path; if it cannot be parsed as one, or value cannot be
+// encoded, an error is returned and nothing is written.
+func Created(w http.ResponseWriter, location string, value interface{}) error {
+	if err := validLocation(location); err != nil {
+		return err
+	}
+	w.Header().Set("Location", location)
+	if err := JSONResponse(w, http.StatusCreated, value); err != nil {
+		w.Header().Del("Location")
+		return err
+	}
+	return nil
+}
+
+// validLocation checks that location is suitable for a Location header.
+func validLocation(location string) error {
+	if location == "" {
+		return errors.New("spirytus: empty location")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("spirytus: invalid location: %w", err)
+	}
+	if u.Scheme == "" && u.Host == "" && u.Path == "" {
+		return fmt.Errorf("spirytus: invalid location %q: no path", location)
+	}
+	return nil
+}
+
+// JSONRawValidate makes JSONRaw and JSONRawBytes check that the JSON they are
+// given is well-formed before writing it, which is useful while debugging.
+// It should be set during program initialisation.
+var JSONRawValidate = false
+
+// JSONRaw writes a response with the provided status code whose body is the
+// pre-encoded JSON read from r, such as a cached payload, without decoding
+// and re-encoding it. The JSON is trusted to be well-formed unless
+// JSONRawValidate is set, in which case malformed JSON results in an error
+// wrapping ErrMalformedJSON and nothing being written. If JSONTrailingNewline
+// is set, a newline is added unless the JSON already ends with one.
+func JSONRaw(w http.ResponseWriter, code int, r io.Reader) error {
+	if JSONRawValidate {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return JSONRawBytes(w, code, b)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	lw := &lastByteWriter{w: w}
+	if _, err := io.Copy(lw, r); err != nil {
+		return err
+	}
+	if JSONTrailingNewline && lw.n > 0 && lw.last != '\n' {
+		_, err := w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+// lastByteWriter remembers the last byte written through it.
+type lastByteWriter struct {
+	w    io.Writer
+	n    int64
+	last byte
+}
+
+func (w *lastByteWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		w.n += int64(n)
+		w.last = p[n-1]
+	}
+	return n, err
+}
+
+// JSONRawBytes is like JSONRaw but takes the JSON as a byte slice.
+func JSONRawBytes(w http.ResponseWriter, code int, b []byte) error {
+	if JSONRawValidate && !json.Valid(b) {
+		return fmt.Errorf("%w: invalid raw JSON response", ErrMalformedJSON)
+	}
+	if JSONTrailingNewline && len(b) > 0 && b[len(b)-1] != '\n' {
+		b = append(b[:len(b):len(b)], '\n')
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, err := w.Write(b)
+	return err
+}
+
+// Push initiates HTTP/2 server pushes of the given asset paths. If the
+// connection does not support push, as with HTTP/1 or when the client has
+// disabled it, ErrPushNotSupported is returned and callers may ignore it.
+// Otherwise the first error from pushing a target is returned.
+func Push(w http.ResponseWriter, targets ...string) error {
+	p, ok := w.(http.Pusher)
+	if !ok {
+		return ErrPushNotSupported
+	}
+	for _, t := range targets {
+		if err := p.Push(t, nil); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				return ErrPushNotSupported
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// CollectionMeta describes the page of a collection returned by
+// CollectionResponse.
+type CollectionMeta struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"per_page"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// CollectionResponse writes a JSON response for a list endpoint in a standard
+// envelope, {"data": [...], "page": n, "per_page": m, "total": t}, with the
+// cursors of meta included when set. A nil items, including a nil slice, is
+// written as an empty array.
+func CollectionResponse(w http.ResponseWriter, code int, items interface{}, meta CollectionMeta) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if string(data) == "null" {
+		data = []byte("[]")
+	}
+	return JSONResponse(w, code, struct {
+		Data json.RawMessage `json:"data"`
+		CollectionMeta
+	}{data, meta})
+}
+
+// RedirectPreserveMethod redirects the client to url with a status code that
+// requires it to repeat the request with the same method and body: 308
+// Permanent Redirect if permanent is true and 307 Temporary Redirect
+// otherwise. Unlike 301 and 302, these do not allow a client to turn a POST
+// in to a GET. If url cannot be parsed as an absolute URL or path, an error
+// is returned and nothing is written.
+func RedirectPreserveMethod(w http.ResponseWriter, req *http.Request, url string, permanent bool) error {
+	if err := validLocation(url); err != nil {
+		return err
+	}
+	code := http.StatusTemporaryRedirect
+	if permanent {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, req, url, code)
+	return nil
+}
@@ -0,0 +1,94 @@
+package spirytus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPreserveMethod(t *testing.T) {
+	tests := []struct {
+		permanent bool
+		want      int
+	}{
+		{false, 307},
+		{true, 308},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/old", strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		if err := RedirectPreserveMethod(w, req, "/new", tt.permanent); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tt.want {
+			t.Errorf("permanent=%v: got status %d, want %d", tt.permanent, w.Code, tt.want)
+		}
+		if loc := w.Header().Get("Location"); loc != "/new" {
+			t.Errorf("permanent=%v: got Location %q, want /new", tt.permanent, loc)
+		}
+	}
+}
+
+func TestRedirectPreserveMethodInvalid(t *testing.T) {
+	for _, url := range []string{"", "http://[::1", "?q=1"} {
+		req := httptest.NewRequest("POST", "/old", nil)
+		w := httptest.NewRecorder()
+		if err := RedirectPreserveMethod(w, req, url, true); err == nil {
+			t.Errorf("%q: expected an error", url)
+		}
+		if w.Code != 200 || len(w.Header()) != 0 || w.Body.Len() != 0 {
+			t.Errorf("%q: response written: %d %v %q", url, w.Code, w.Header(), w.Body)
+		}
+	}
+}
+
+func TestUpgradeRequired(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := UpgradeRequired(w, "websocket", "h2c"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 426 || w.Header().Get("Upgrade") != "websocket, h2c" {
+		t.Errorf("got %d with Upgrade %q", w.Code, w.Header().Get("Upgrade"))
+	}
+	if body := w.Body.String(); body != `{"error":"Upgrade required","upgrade":["websocket","h2c"]}` {
+		t.Errorf("got body %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	if err := UpgradeRequired(w); err == nil {
+		t.Error("expected an error without protocols")
+	}
+	if len(w.Header()) != 0 || w.Body.Len() != 0 {
+		t.Errorf("response written without protocols: %v %q", w.Header(), w.Body)
+	}
+}
+
+func TestJSONRawTrailingNewline(t *testing.T) {
+	defer func(v bool) { JSONTrailingNewline = v }(JSONTrailingNewline)
+	tests := []struct {
+		newline    bool
+		body, want string
+	}{
+		{false, `{"a":1}`, `{"a":1}`},
+		{true, `{"a":1}`, "{\"a\":1}\n"},
+		{true, "{\"a\":1}\n", "{\"a\":1}\n"},
+		{true, ``, ``},
+	}
+	for _, tt := range tests {
+		JSONTrailingNewline = tt.newline
+		w := httptest.NewRecorder()
+		if err := JSONRaw(w, 200, strings.NewReader(tt.body)); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("JSONRaw(%q) with newline=%v wrote %q, want %q", tt.body, tt.newline, got, tt.want)
+		}
+		w = httptest.NewRecorder()
+		if err := JSONRawBytes(w, 200, []byte(tt.body)); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("JSONRawBytes(%q) with newline=%v wrote %q, want %q", tt.body, tt.newline, got, tt.want)
+		}
+	}
+}
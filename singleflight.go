@@ -0,0 +1,78 @@
+package spirytus
+
+import (
+	"net/http"
+	"sync"
+)
+
+// SingleFlight returns a middleware that coalesces concurrent GET and HEAD
+// requests with the same key, so that the handler runs once and every waiting
+// request receives a copy of its response. The key of a request is given by
+// keyFn; requests for which it returns the empty string, and requests with
+// other methods, are passed through.
+//
+// Waiting requests receive the response computed for another request, which
+// may be slightly stale by the time they would have started and carries all
+// of its headers, including cookies. The key must therefore capture
+// everything the response depends on, such as the URL and the identity of the
+// user. If the handler panics, the waiting requests each run the handler
+// themselves.
+func SingleFlight(keyFn func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		g := &flightGroup{calls: make(map[string]*flightCall)}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			key := keyFn(req)
+			if key == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			g.serve(next, w, req, req.Method+" "+key)
+		})
+	}
+}
+
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	resp *RecordedResponse // nil if the handler panicked
+}
+
+func (g *flightGroup) serve(next http.Handler, w http.ResponseWriter, req *http.Request, key string) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-req.Context().Done():
+			return
+		}
+		if c.resp == nil {
+			next.ServeHTTP(w, req)
+		} else {
+			c.resp.Replay(w)
+		}
+		return
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	rec := newResponseRecorder()
+	next.ServeHTTP(rec, req)
+	c.resp = rec.response()
+	c.resp.Replay(w)
+}
@@ -10,13 +10,43 @@ import (
 	"net/http"
 )
 
+// JSONTrailingNewline makes JSONResponse terminate the encoded value with a
+// newline, as json.Encoder does, which suits line-oriented tools. It should be
+// set during program initialisation.
+var JSONTrailingNewline = false
+
+// An Enveloper wraps the values written by JSONResponse in an envelope, such
+// as {"data": value}. It must not change the status code of the response.
+type Enveloper interface {
+	Envelope(code int, value interface{}) interface{}
+}
+
+// EnveloperFunc adapts an ordinary function to the Enveloper interface.
+type EnveloperFunc func(code int, value interface{}) interface{}
+
+func (f EnveloperFunc) Envelope(code int, value interface{}) interface{} {
+	return f(code, value)
+}
+
+// ResponseEnvelope, if not nil, wraps every value written by JSONResponse,
+// including the error responses written by the package, which it can tell
+// apart by their status code. By default values are written as they are.
+// It should be set during program initialisation.
+var ResponseEnvelope Enveloper
+
 // JSONResponse writes a JSON-encoded response with the provided status code to the ResponseWriter.
 // If the value cannot be encoded an error is returned and nothing is written to the writer.
 func JSONResponse(w http.ResponseWriter, code int, value interface{}) error {
+	if ResponseEnvelope != nil {
+		value = ResponseEnvelope.Envelope(code, value)
+	}
 	v, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
+	if JSONTrailingNewline {
+		v = append(v, '\n')
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -24,38 +54,227 @@ func JSONResponse(w http.ResponseWriter, code int, value interface{}) error {
 	return nil
 }
 
+// jsonError writes a JSON error response of the form {"error": msg}.
+func jsonError(w http.ResponseWriter, code int, msg string) error {
+	return JSONResponse(w, code, errorBody{Error: msg})
+}
+
+type errorBody struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 // JSONRequest reads the body of req in to v using a JSON decoder.
+// If the body is empty ErrEmptyBody is returned, and if it is not valid JSON
+// the error wraps ErrMalformedJSON.
 func JSONRequest(req *http.Request, v interface{}) error {
 	dec := json.NewDecoder(req.Body)
-	return dec.Decode(v)
+	return decodeError(dec.Decode(v))
+}
+
+// JSONRequestNumber is like JSONRequest but decodes JSON numbers in to
+// interface{} values as json.Number rather than float64. Use it when numbers
+// may not survive the conversion to float64, such as 64-bit IDs in a
+// map[string]interface{}.
+func JSONRequestNumber(req *http.Request, v interface{}) error {
+	dec := json.NewDecoder(req.Body)
+	dec.UseNumber()
+	return decodeError(dec.Decode(v))
 }
 
 // A resource describes an HTTP endpoint that can respond to a set of methods.
 // It is a regular http.Handler so can be used with any router.
+//
+// Method handlers are passed the ResponseWriter given to ServeHTTP unchanged,
+// so a handler may take over the connection through http.Hijacker, for
+// example to upgrade it to a WebSocket.
 type Resource struct {
 	allow   string
 	methods []methodHandler
+	header  http.Header
+
+	// index maps methods to their position in methods once there are
+	// more than methodIndexThreshold of them, when a linear scan becomes
+	// slower than a map lookup.
+	index map[string]int
+
+	optionsStatus int
 }
 
+// methodIndexThreshold is where BenchmarkResourceLookup shows the two break
+// even for the last method registered: the scan wins clearly at four methods,
+// the map at sixteen.
+const methodIndexThreshold = 8
+
 type methodHandler struct {
-	method  string
+	method   string
+	handler  http.Handler // nil if only matchers are registered
+	matchers []matchHandler
+}
+
+type matchHandler struct {
+	match   func(*http.Request) bool
 	handler http.Handler
 }
 
 // Handle instructs the resource to handle the given method with a handler.
+// It panics if handler is nil, including a nil http.HandlerFunc. Other nil
+// values wrapped in a non-nil http.Handler are not detected.
 func (r *Resource) Handle(method string, handler http.Handler) {
-	h := methodHandler{method, handler}
-	for i, m := range r.methods {
-		if m.method == method {
-			r.methods[i] = h
-			return
-		}
+	if isNilHandler(handler) {
+		panic("spirytus: nil handler for method " + method)
+	}
+	r.methodHandler(method).handler = handler
+}
+
+// HandleMatch instructs the resource to handle requests with the given method
+// that satisfy match with a handler, for example to dispatch on the
+// Content-Type of the request. When the method matches, the predicates are
+// tried in the order they were registered and the handler of the first one to
+// return true is called. If none does, the handler registered with Handle is
+// called, or 415 Unsupported Media Type is returned if there is none.
+// It panics if match or handler is nil, as Handle does.
+func (r *Resource) HandleMatch(method string, match func(*http.Request) bool, handler http.Handler) {
+	if match == nil || isNilHandler(handler) {
+		panic("spirytus: nil matcher or handler for method " + method)
+	}
+	m := r.methodHandler(method)
+	m.matchers = append(m.matchers, matchHandler{match, handler})
+}
+
+func isNilHandler(h http.Handler) bool {
+	f, ok := h.(http.HandlerFunc)
+	return h == nil || ok && f == nil
+}
+
+// methodHandler returns the entry for method, adding it if necessary.
+func (r *Resource) methodHandler(method string) *methodHandler {
+	if m := r.lookup(method); m != nil {
+		return m
 	}
-	r.methods = append(r.methods, h)
+	r.methods = append(r.methods, methodHandler{method: method})
 	if r.allow != "" {
-		method = ", " + method
+		r.allow += ", "
 	}
 	r.allow += method
+
+	if r.index != nil {
+		r.index[method] = len(r.methods) - 1
+	} else if len(r.methods) > methodIndexThreshold {
+		r.index = make(map[string]int, len(r.methods))
+		for i, m := range r.methods {
+			r.index[m.method] = i
+		}
+	}
+	return &r.methods[len(r.methods)-1]
+}
+
+// lookup returns the entry for method, or nil if it is not handled.
+func (r *Resource) lookup(method string) *methodHandler {
+	if r == nil {
+		return nil
+	}
+	if r.index != nil {
+		if i, ok := r.index[method]; ok {
+			return &r.methods[i]
+		}
+		return nil
+	}
+	for i := range r.methods {
+		if r.methods[i].method == method {
+			return &r.methods[i]
+		}
+	}
+	return nil
+}
+
+func (m *methodHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for _, mh := range m.matchers {
+		if mh.match(req) {
+			mh.handler.ServeHTTP(w, req)
+			return
+		}
+	}
+	if m.handler == nil {
+		http.Error(w, "Unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+	m.handler.ServeHTTP(w, req)
+}
+
+// Match returns the handler the resource dispatches requests with the given
+// method to, without serving a request, and whether there is one. For a
+// method registered only with Handle this is the registered handler itself;
+// if HandleMatch was used, it is a handler that applies the predicates.
+func (r *Resource) Match(method string) (http.Handler, bool) {
+	m := r.lookup(method)
+	if m == nil {
+		return nil, false
+	}
+	if len(m.matchers) == 0 {
+		return m.handler, m.handler != nil
+	}
+	mh := *m
+	return &mh, true
+}
+
+// Methods returns the methods handled by the resource in the order they were
+// first registered.
+func (r *Resource) Methods() []string {
+	if r == nil {
+		return nil
+	}
+	methods := make([]string, len(r.methods))
+	for i, m := range r.methods {
+		methods[i] = m.method
+	}
+	return methods
+}
+
+// SetDefaultHeader sets a header that is added to every response from the
+// resource before the method handler is called, so the handler may still
+// change or delete it. Default headers are also sent with the resource's
+// OPTIONS and 405 Method Not Allowed responses, but not with the 404 response
+// of a resource without handlers.
+func (r *Resource) SetDefaultHeader(key, value string) {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	r.header.Set(key, value)
+}
+
+// SetOptionsStatus sets the status code of the resource's responses to
+// OPTIONS requests, which is 200 OK by default. Some CORS clients expect
+// 204 No Content for preflight requests:
+//
+//	r.SetOptionsStatus(http.StatusNoContent)
+func (r *Resource) SetOptionsStatus(code int) {
+	r.optionsStatus = code
+}
+
+// Clone returns a copy of the resource. Handlers registered on either
+// resource after the call do not affect the other.
+func (r *Resource) Clone() *Resource {
+	if r == nil {
+		return nil
+	}
+	c := &Resource{
+		allow:   r.allow,
+		methods: append([]methodHandler(nil), r.methods...),
+		header:  r.header.Clone(),
+
+		optionsStatus: r.optionsStatus,
+	}
+	for i := range c.methods {
+		c.methods[i].matchers = append([]matchHandler(nil), c.methods[i].matchers...)
+	}
+	if r.index != nil {
+		c.index = make(map[string]int, len(r.index))
+		for k, v := range r.index {
+			c.index[k] = v
+		}
+	}
+	return c
 }
 
 func (r *Resource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -64,16 +283,21 @@ func (r *Resource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if r.header != nil {
+		h := w.Header()
+		for k, v := range r.header {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+
 	if req.Method == "OPTIONS" {
 		r.serveOptions(w, req)
 		return
 	}
 
-	for _, m := range r.methods {
-		if req.Method == m.method {
-			m.handler.ServeHTTP(w, req)
-			return
-		}
+	if m := r.lookup(req.Method); m != nil {
+		m.ServeHTTP(w, req)
+		return
 	}
 	w.Header().Set("Allow", r.allow)
 	http.Error(w, "Not allowed", http.StatusMethodNotAllowed)
@@ -81,7 +305,11 @@ func (r *Resource) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 func (r *Resource) serveOptions(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Allow", r.allow)
-	w.WriteHeader(http.StatusOK)
+	code := r.optionsStatus
+	if code == 0 {
+		code = http.StatusOK
+	}
+	w.WriteHeader(code)
 	return
 }
 
@@ -0,0 +1,138 @@
+package spirytus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// discardWriter is a ResponseWriter that throws the response away, so that
+// benchmarks measure the handler rather than a recorder.
+type discardWriter struct {
+	header http.Header
+}
+
+func newDiscardWriter() *discardWriter {
+	return &discardWriter{header: make(http.Header)}
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+var noopHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+// benchResource returns a resource handling several methods.
+func benchResource() *Resource {
+	r := new(Resource)
+	for _, method := range []string{"GET", "HEAD", "POST", "PUT", "DELETE"} {
+		r.Handle(method, noopHandler)
+	}
+	return r
+}
+
+func BenchmarkResourceServeHTTPParallel(b *testing.B) {
+	r := benchResource()
+	for _, method := range []string{"OPTIONS", "PATCH"} {
+		b.Run(method, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				w := newDiscardWriter()
+				req := httptest.NewRequest(method, "/", nil)
+				for pb.Next() {
+					r.ServeHTTP(w, req)
+				}
+			})
+		})
+	}
+}
+
+var lookupMethods = []string{
+	"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "TRACE",
+	"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK", "REPORT",
+}
+
+// BenchmarkResourceLookup compares the linear scan and the map index for
+// resources with n methods, looking up the last one registered. It is the
+// basis for methodIndexThreshold.
+func BenchmarkResourceLookup(b *testing.B) {
+	for _, n := range []int{4, 8, 16} {
+		r := new(Resource)
+		for _, method := range lookupMethods[:n] {
+			r.Handle(method, noopHandler)
+		}
+		last := r.methods[n-1].method
+		index := make(map[string]int, n)
+		for i, m := range r.methods {
+			index[m.method] = i
+		}
+		for _, useIndex := range []bool{false, true} {
+			name := fmt.Sprintf("scan/%d", n)
+			if useIndex {
+				name = fmt.Sprintf("map/%d", n)
+			}
+			b.Run(name, func(b *testing.B) {
+				r.index = nil
+				if useIndex {
+					r.index = index
+				}
+				for i := 0; i < b.N; i++ {
+					if r.lookup(last) == nil {
+						b.Fatal("method not found")
+					}
+				}
+			})
+		}
+	}
+}
+
+func benchmarkServeHTTP(b *testing.B, method string) {
+	r := benchResource()
+	w := newDiscardWriter()
+	req := httptest.NewRequest(method, "/", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkResourceServeHTTPHit(b *testing.B)              { benchmarkServeHTTP(b, "PUT") }
+func BenchmarkResourceServeHTTPMethodNotAllowed(b *testing.B) { benchmarkServeHTTP(b, "PATCH") }
+func BenchmarkResourceServeHTTPOptions(b *testing.B)          { benchmarkServeHTTP(b, "OPTIONS") }
+
+func TestServeHTTPHitAllocs(t *testing.T) {
+	r := benchResource()
+	w := newDiscardWriter()
+	req := httptest.NewRequest("PUT", "/", nil)
+	if n := testing.AllocsPerRun(100, func() { r.ServeHTTP(w, req) }); n != 0 {
+		t.Errorf("ServeHTTP allocated %v times per request, want 0", n)
+	}
+}
+
+func TestHandleNilPanics(t *testing.T) {
+	always := func(*http.Request) bool { return true }
+	tests := []struct {
+		name     string
+		register func(r *Resource)
+		want     string
+	}{
+		{"nil", func(r *Resource) { r.Handle("POST", nil) }, "spirytus: nil handler for method POST"},
+		{"HandlerFunc", func(r *Resource) { r.Handle("POST", http.HandlerFunc(nil)) }, "spirytus: nil handler for method POST"},
+		{"HandleMatch", func(r *Resource) { r.HandleMatch("PUT", always, http.HandlerFunc(nil)) }, "spirytus: nil matcher or handler for method PUT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(Resource)
+			defer func() {
+				if p := recover(); p != tt.want {
+					t.Errorf("panic = %v, want %q", p, tt.want)
+				}
+				if len(r.methods) != 0 {
+					t.Errorf("resource has methods %v after panic", r.Methods())
+				}
+			}()
+			tt.register(r)
+		})
+	}
+}
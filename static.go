@@ -0,0 +1,134 @@
+package spirytus
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+)
+
+// StaticFS returns a resource that serves GET and HEAD requests with files
+// from fsys, so that assets embedded with embed.FS can be served alongside
+// other resources. The request path, relative to the root of fsys, names the
+// file to serve; use http.StripPrefix to mount it below a path. Other methods
+// receive the usual 405 response.
+//
+// Directory listings are never served. A request for a directory serves the
+// file named index within it, or responds with 404 if index is empty or no
+// such file exists.
+func StaticFS(fsys fs.FS, index string) *Resource {
+	h := &staticHandler{fsys, index}
+	r := new(Resource)
+	r.Handle("GET", h)
+	r.Handle("HEAD", h)
+	return r
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	index string
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Cleaning a rooted path resolves every "..", so the name cannot
+	// escape the root of the file system.
+	name := path.Clean("/" + req.URL.Path)[1:]
+	if name == "" {
+		name = "."
+	}
+
+	f, fi, err := h.open(name)
+	if err == nil && fi.IsDir() {
+		f.Close()
+		if h.index == "" {
+			err = fs.ErrNotExist
+		} else if f, fi, err = h.open(path.Join(name, h.index)); err == nil && fi.IsDir() {
+			f.Close()
+			err = fs.ErrNotExist
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	http.ServeContent(w, req, fi.Name(), fi.ModTime(), content)
+}
+
+func (h *staticHandler) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+// ServeSeeker serves content with http.ServeContent, which handles Range
+// requests and the conditional headers If-Match, If-None-Match,
+// If-Modified-Since and If-Unmodified-Since. Setting the ETag header before
+// the call makes the entity tag take part, and setting Content-Type overrides
+// the type otherwise deduced from name or the content itself.
+//
+// Unlike http.ServeContent, an error is returned if content cannot be sought
+// or read, so the caller can log it. The content is checked to be seekable
+// before anything is written, so in that case the caller may still respond;
+// errors while serving leave the response as http.ServeContent wrote it.
+func ServeSeeker(w http.ResponseWriter, req *http.Request, name string, modtime time.Time, content io.ReadSeeker) error {
+	if _, err := content.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := &errReadSeeker{ReadSeeker: content}
+	http.ServeContent(w, req, name, modtime, r)
+	return r.err
+}
+
+// errReadSeeker records the first error other than io.EOF returned by
+// the underlying ReadSeeker.
+type errReadSeeker struct {
+	io.ReadSeeker
+	err error
+}
+
+func (r *errReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *errReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.ReadSeeker.Seek(offset, whence)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,21 @@
+package spirytus
+
+// IsSuccess reports whether code is a 2xx status code.
+func IsSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// IsRedirect reports whether code is a 3xx status code.
+func IsRedirect(code int) bool {
+	return code >= 300 && code < 400
+}
+
+// IsClientError reports whether code is a 4xx status code.
+func IsClientError(code int) bool {
+	return code >= 400 && code < 500
+}
+
+// IsServerError reports whether code is a 5xx status code.
+func IsServerError(code int) bool {
+	return code >= 500 && code < 600
+}
@@ -0,0 +1,160 @@
+package spirytus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientTimeout returns a middleware that lets clients choose how long the
+// server may spend on their request. The named header holds a duration,
+// either in Go syntax such as "1.5s" or "300ms", or as a plain number of
+// seconds. It is clamped to max and set as the deadline of the request
+// context. A request with a missing or unparseable header gets a deadline of
+// fallback instead, or none if fallback is zero.
+//
+// If header is Grpc-Timeout, its value is instead parsed as gRPC specifies:
+// up to eight digits followed by one of the units H, M, S, m, u or n, for
+// hours, minutes, seconds, milliseconds, microseconds and nanoseconds.
+//
+// The deadline is cooperative: the handler must observe cancellation of the
+// request context. If it returns after the deadline has passed without having
+// written a response, a 504 Gateway Timeout response is written for it.
+func ClientTimeout(header string, max, fallback time.Duration) Middleware {
+	parse := parseTimeout
+	if http.CanonicalHeaderKey(header) == "Grpc-Timeout" {
+		parse = parseGRPCTimeout
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			d, ok := parse(req.Header.Get(header))
+			if !ok {
+				d = fallback
+			} else if d > max {
+				d = max
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+			serveWithDeadline(next, w, req, d)
+		})
+	}
+}
+
+// parseTimeout parses a timeout given as a Go duration or a number of
+// seconds. Only positive timeouts are valid.
+func parseTimeout(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		secs, err := strconv.ParseFloat(s, 64)
+		if err != nil || secs > float64(1<<63-1)/float64(time.Second) {
+			return 0, false
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	return d, d > 0
+}
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses the value of a gRPC Grpc-Timeout header. Only
+// positive timeouts are valid.
+func parseGRPCTimeout(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || len(s) > 9 {
+		return 0, false
+	}
+	unit, ok := grpcTimeoutUnits[s[len(s)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	// Eight digits of hours overflow a Duration; saturate instead.
+	if n > uint64(1<<63-1)/uint64(unit) {
+		return 1<<63 - 1, true
+	}
+	d := time.Duration(n) * unit
+	return d, d > 0
+}
+
+// serveWithDeadline calls next with a request whose context expires after d,
+// responding with 504 if next gives up after the deadline without writing
+// anything or hijacking the connection.
+func serveWithDeadline(next http.Handler, w http.ResponseWriter, req *http.Request, d time.Duration) {
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	defer cancel()
+	rw := &responseWriter{ResponseWriter: w}
+	next.ServeHTTP(rw, req.WithContext(ctx))
+	if rw.code == 0 && !rw.hijacked && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		jsonError(w, http.StatusGatewayTimeout, "Request timed out")
+	}
+}
+
+// HardDeadline returns a middleware that guarantees a response within d,
+// even from a handler that is stuck and ignores its context. The handler runs
+// in its own goroutine with a context that expires after d, and its response
+// is buffered. If it has not finished by the deadline, 503 Service Unavailable
+// is written and the buffered response is discarded.
+//
+// Where ClientTimeout trusts the handler to observe cancellation and lets it
+// write directly to the connection, HardDeadline trusts nothing, at two costs.
+// A handler that never returns leaks its goroutine, and everything it holds,
+// until it does. And since the response is buffered, handlers cannot stream,
+// flush or hijack the connection. It is meant as a last line of defence with
+// a generous d, not as the primary timeout.
+//
+// A panic in the handler is raised again on the goroutine that called
+// HardDeadline as a *PanicError carrying the stack of the handler, which
+// Recover reports in place of its own.
+func HardDeadline(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			defer cancel()
+
+			rec := newResponseRecorder()
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							p = &PanicError{Value: p, Stack: debug.Stack()}
+						}
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(rec, req.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				rec.response().Replay(w)
+			case p := <-panicked:
+				panic(p)
+			case <-ctx.Done():
+				jsonError(w, http.StatusServiceUnavailable, "Request timed out")
+			}
+		})
+	}
+}
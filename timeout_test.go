@@ -0,0 +1,64 @@
+package spirytus
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"100m", 100 * time.Millisecond, true},
+		{"2S", 2 * time.Second, true},
+		{"3M", 3 * time.Minute, true},
+		{"1H", time.Hour, true},
+		{"50u", 50 * time.Microsecond, true},
+		{"7n", 7, true},
+		{"99999999H", 1<<63 - 1, true},
+		{"0S", 0, false},
+		{"100", 0, false},
+		{"1.5S", 0, false},
+		{"-1S", 0, false},
+		{"123456789S", 0, false},
+		{"10s", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		d, ok := parseGRPCTimeout(tt.s)
+		if d != tt.want || ok != tt.ok {
+			t.Errorf("parseGRPCTimeout(%q) = %v, %v, want %v, %v", tt.s, d, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that pretends to support hijacking.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (w hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestClientTimeoutHijacked(t *testing.T) {
+	h := ClientTimeout("X-Timeout", time.Second, 0)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatal(err)
+		}
+		<-req.Context().Done()
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Timeout", "1ms")
+	w := hijackRecorder{httptest.NewRecorder()}
+	h.ServeHTTP(w, req)
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Errorf("response written after hijack: %v %q", w.Header(), w.Body)
+	}
+}
@@ -0,0 +1,88 @@
+package spirytus
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTiming is a middleware that reports the durations recorded by the
+// handler with RecordTiming to the client in a Server-Timing header. Only
+// durations recorded before the handler starts writing the response can be
+// included.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t := new(serverTimings)
+		rw := &responseWriter{ResponseWriter: w, beforeHeader: func(h http.Header) {
+			if v := t.header(); v != "" {
+				h.Add("Server-Timing", v)
+			}
+		}}
+		ctx := context.WithValue(req.Context(), timingKey, t)
+		next.ServeHTTP(rw, req.WithContext(ctx))
+		rw.finish()
+	})
+}
+
+// RecordTiming records that the operation name took d, to be reported by the
+// ServerTiming middleware. The name must be an HTTP token, such as "db" or
+// "cache-read"; other names are ignored. RecordTiming may be called from
+// multiple goroutines and does nothing if ctx does not come from a request
+// handled by ServerTiming.
+func RecordTiming(ctx context.Context, name string, d time.Duration) {
+	t, _ := ctx.Value(timingKey).(*serverTimings)
+	if t == nil || !isToken(name) {
+		return
+	}
+	t.mu.Lock()
+	t.metrics = append(t.metrics, serverTiming{name, d})
+	t.mu.Unlock()
+}
+
+type serverTimings struct {
+	mu      sync.Mutex
+	metrics []serverTiming
+}
+
+type serverTiming struct {
+	name string
+	dur  time.Duration
+}
+
+// header formats the recorded metrics as a Server-Timing header value, with
+// durations in milliseconds.
+func (t *serverTimings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	for i, m := range t.metrics {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(m.name)
+		b.WriteString(";dur=")
+		ms := float64(m.dur.Microseconds()) / 1000
+		b.WriteString(strconv.FormatFloat(ms, 'f', -1, 64))
+	}
+	return b.String()
+}
+
+// isToken reports whether s is a token as defined by RFC 7230.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
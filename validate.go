@@ -0,0 +1,290 @@
+package spirytus
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Validator can check itself for semantic errors after being decoded.
+type Validator interface {
+	Validate() error
+}
+
+// JSONRequestValidate reads the body of req in to v like JSONRequest and, if
+// v implements Validator, returns the result of its Validate method.
+func JSONRequestValidate(req *http.Request, v interface{}) error {
+	if err := JSONRequest(req, v); err != nil {
+		return err
+	}
+	if val, ok := v.(Validator); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+// Bind populates v, which should be a pointer to a struct, from req in one
+// call. A JSON body, if present, is decoded in to v as by JSONRequest. Then
+// every field tagged with a query parameter name, such as
+//
+//	Limit int `query:"limit"`
+//
+// is set from that parameter if the request has it, so query parameters
+// override the body. If both succeed and v implements Validator, it is
+// validated. Errors from decoding the body and binding the query are joined
+// so the client learns about all of them at once; query parameters that do
+// not parse are reported together as a *ValidationError naming each one.
+//
+// A query field may be a string, bool, integer or floating-point type, a type
+// implementing encoding.TextUnmarshaler, a pointer to one of these, which is
+// allocated only if the parameter is present, or a slice of them, which
+// receives every value of the parameter. Other field types result in an
+// error. Fields of embedded structs are bound too. Each source is optional: a
+// request without a body, or a struct without query tags, simply skips that
+// step. There are no path parameters to bind since Mux matches exact paths.
+//
+// Like JSONResponseFields, Bind trades the package's avoidance of reflection
+// for ergonomics: the alternative is parsing code in every handler.
+func Bind(req *http.Request, v interface{}) error {
+	var errs []error
+	if req.Body != nil && req.Body != http.NoBody {
+		if err := JSONRequest(req, v); err != nil && !errors.Is(err, ErrEmptyBody) {
+			errs = append(errs, err)
+		}
+	}
+	if err := bindQuery(req.URL.Query(), v); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if val, ok := v.(Validator); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// bindQuery sets the fields of the struct v points to from their tagged
+// query parameters. Values that do not parse are collected in a
+// *ValidationError.
+func bindQuery(query url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var verrs ValidationError
+	if err := bindQueryStruct(query, rv.Elem(), &verrs); err != nil {
+		return err
+	}
+	return verrs.Err()
+}
+
+func bindQueryStruct(query url.Values, sv reflect.Value, verrs *ValidationError) error {
+	t := sv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("query"), ",")
+		if name == "" || name == "-" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				if err := bindQueryStruct(query, sv.Field(i), verrs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		values := query[name]
+		if len(values) == 0 || !f.IsExported() {
+			continue
+		}
+		ok, err := setQueryField(sv.Field(i), values)
+		if !ok {
+			return fmt.Errorf("spirytus: cannot bind query parameter %q to field %s of type %s", name, f.Name, f.Type)
+		}
+		if err != nil {
+			verrs.Add(name, queryTypeError(f.Type))
+		}
+	}
+	return nil
+}
+
+// setQueryField sets v from the values of a query parameter. It reports
+// false if the type of v is not supported.
+func setQueryField(v reflect.Value, values []string) (bool, error) {
+	t := v.Type()
+	if t.Kind() != reflect.Slice || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return setQueryValue(v, values[0])
+	}
+	s := reflect.MakeSlice(t, len(values), len(values))
+	for i, value := range values {
+		if ok, err := setQueryValue(s.Index(i), value); !ok || err != nil {
+			return ok, err
+		}
+	}
+	v.Set(s)
+	return true, nil
+}
+
+// setQueryValue sets v from a single query value. It reports false if the
+// type of v is not supported.
+func setQueryValue(v reflect.Value, s string) (bool, error) {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		ok, err := setQueryValue(p.Elem(), s)
+		if ok && err == nil {
+			v.Set(p)
+		}
+		return ok, err
+	}
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return true, tu.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return true, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		v.SetFloat(f)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// queryTypeError describes the value expected for a query field of type t.
+func queryTypeError(t reflect.Type) string {
+	if t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "invalid value"
+	}
+	return "expected " + jsonTypeName(t)
+}
+
+// A FieldDetail describes a problem with a single field of a request.
+type FieldDetail struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// FieldError translates an error from decoding a JSON request, such as one
+// returned by JSONRequest, in to a description of the offending field that
+// is suitable for the client. The field is given as a dotted path of JSON
+// member names, for example {"field": "user.age", "error": "expected integer"}.
+// The boolean is false if err is not a type mismatch for a field.
+func FieldError(err error) (*FieldDetail, bool) {
+	var ute *json.UnmarshalTypeError
+	if !errors.As(err, &ute) || ute.Type == nil {
+		return nil, false
+	}
+	return &FieldDetail{Field: ute.Field, Error: "expected " + jsonTypeName(ute.Type)}, true
+}
+
+// jsonTypeName returns the name of the JSON type that decodes in to t.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "base64 string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return t.String()
+}
+
+// A ValidationError collects problems with the fields of a request so that
+// they can all be reported to the client at once. Its zero value is ready to
+// use:
+//
+//	func (p *Person) Validate() error {
+//		var errs spirytus.ValidationError
+//		if p.Name == "" {
+//			errs.Add("name", "is required")
+//		}
+//		return errs.Err()
+//	}
+//
+// It marshals to JSON as {"error": "Validation failed", "fields": [...]}
+// with one FieldDetail per problem, in the order they were added.
+type ValidationError struct {
+	Fields []FieldDetail
+}
+
+// Add records a problem with the named field.
+func (e *ValidationError) Add(field, msg string) {
+	e.Fields = append(e.Fields, FieldDetail{field, msg})
+}
+
+// Err returns e if any problems have been added and nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Error
+	}
+	return "spirytus: validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) MarshalJSON() ([]byte, error) {
+	fields := e.Fields
+	if fields == nil {
+		fields = []FieldDetail{}
+	}
+	return json.Marshal(struct {
+		Error  string        `json:"error"`
+		Fields []FieldDetail `json:"fields"`
+	}{"Validation failed", fields})
+}
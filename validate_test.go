@@ -0,0 +1,105 @@
+package spirytus
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPage struct {
+	Limit  int  `query:"limit"`
+	Offset uint `query:"offset"`
+}
+
+type bindParams struct {
+	bindPage
+	Name    string     `json:"name" query:"name"`
+	Active  *bool      `query:"active"`
+	Ratio   float64    `query:"ratio"`
+	Tags    []string   `query:"tag"`
+	IDs     []int64    `query:"id"`
+	Since   time.Time  `query:"since"`
+	Until   *time.Time `query:"until"`
+	Ignored string     `query:"-"`
+	Body    string     `json:"body"`
+}
+
+func (p *bindParams) Validate() error {
+	if p.Limit > 100 {
+		return errors.New("limit too large")
+	}
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	req := httptest.NewRequest("POST",
+		"/?limit=10&offset=5&name=query&active=true&ratio=0.5&tag=a&tag=b&id=1&id=2&since=2026-01-02T03:04:05Z&-=x&Ignored=x",
+		strings.NewReader(`{"name":"body","body":"text"}`))
+	p := bindParams{Ratio: 1}
+	if err := Bind(req, &p); err != nil {
+		t.Fatal(err)
+	}
+	active := true
+	want := bindParams{
+		bindPage: bindPage{Limit: 10, Offset: 5},
+		Name:     "query",
+		Active:   &active,
+		Ratio:    0.5,
+		Tags:     []string{"a", "b"},
+		IDs:      []int64{1, 2},
+		Since:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Body:     "text",
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?limit=x&offset=-1&active=maybe&id=1&id=y&until=yesterday", nil)
+	var p bindParams
+	err := Bind(req, &p)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got error %v, want a *ValidationError", err)
+	}
+	want := []FieldDetail{
+		{"limit", "expected integer"},
+		{"offset", "expected integer"},
+		{"active", "expected boolean"},
+		{"id", "expected integer"},
+		{"until", "invalid value"},
+	}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("got fields %v, want %v", verr.Fields, want)
+	}
+	if p.Active != nil || p.IDs != nil || p.Until != nil {
+		t.Errorf("fields set from invalid values: %+v", p)
+	}
+
+	req = httptest.NewRequest("GET", "/?limit=500", nil)
+	if err := Bind(req, &p); err == nil || err.Error() != "limit too large" {
+		t.Errorf("got error %v, want the Validate error", err)
+	}
+
+	var bad struct {
+		M map[string]string `query:"m"`
+	}
+	req = httptest.NewRequest("GET", "/?m=1", nil)
+	if err := Bind(req, &bad); err == nil || errors.As(err, &verr) {
+		t.Errorf("got error %v, want an unsupported type error", err)
+	}
+}
+
+func TestBindJoinsErrors(t *testing.T) {
+	req := httptest.NewRequest("POST", "/?limit=x", strings.NewReader(`{"name":`))
+	var p bindParams
+	err := Bind(req, &p)
+	var verr *ValidationError
+	if !errors.Is(err, ErrMalformedJSON) || !errors.As(err, &verr) {
+		t.Errorf("got error %v, want both body and query errors", err)
+	}
+}
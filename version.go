@@ -0,0 +1,42 @@
+package spirytus
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// RequireVersion returns a middleware that requires requests to name one of
+// the supported API versions in their X-API-Version header. Requests with a
+// missing or unsupported version are rejected with 400 Bad Request and a JSON
+// body listing the supported versions. The version of accepted requests is
+// available to handlers through APIVersion.
+func RequireVersion(supported ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			v := strings.TrimSpace(req.Header.Get("X-API-Version"))
+			for _, s := range supported {
+				if v == s {
+					ctx := context.WithValue(req.Context(), versionKey, v)
+					next.ServeHTTP(w, req.WithContext(ctx))
+					return
+				}
+			}
+			msg := "Unsupported API version"
+			if v == "" {
+				msg = "Missing API version"
+			}
+			JSONResponse(w, http.StatusBadRequest, struct {
+				Error     string   `json:"error"`
+				Supported []string `json:"supported"`
+			}{msg, supported})
+		})
+	}
+}
+
+// APIVersion returns the API version negotiated by RequireVersion, or the
+// empty string if there is none.
+func APIVersion(ctx context.Context) string {
+	v, _ := ctx.Value(versionKey).(string)
+	return v
+}
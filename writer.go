@@ -0,0 +1,98 @@
+package spirytus
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps a ResponseWriter passed to a handler to track the
+// status code and the number of body bytes written. It forwards
+// http.Flusher, http.Hijacker and http.Pusher to the underlying writer and supports
+// http.ResponseController through Unwrap, so wrapping does not hide those
+// capabilities from the handler.
+type responseWriter struct {
+	http.ResponseWriter
+	code     int
+	written  int64
+	hijacked bool
+
+	// beforeHeader, if set, is called with the header just before it is
+	// written, or by finish if the handler wrote nothing.
+	beforeHeader func(http.Header)
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+		w.runBeforeHeader()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) runBeforeHeader() {
+	if f := w.beforeHeader; f != nil {
+		w.beforeHeader = nil
+		f(w.Header())
+	}
+}
+
+// finish must be called once the handler has returned. If the handler wrote
+// nothing, net/http will send the header on its behalf, so beforeHeader is
+// run now.
+func (w *responseWriter) finish() {
+	if w.code == 0 && !w.hijacked {
+		w.runBeforeHeader()
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.code == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		conn, rw, err := hj.Hijack()
+		w.hijacked = err == nil
+		return conn, rw, err
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// countingBody wraps a request body to count the bytes read from it.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}